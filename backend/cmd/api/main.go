@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"fmt"
 	"log"
@@ -46,20 +48,81 @@ func main() {
 	// Create repository
 	eventsRepo := repository.NewPostgresEventsRepository(db)
 
-	// Create a new events handler with the repository
-	eventsHandler := handlers.NewEventsHandler(eventsRepo)
+	// Create a new events handler with the repository; the admin role bypasses organization filters
+	eventsHandler := handlers.NewEventsHandler(eventsRepo, handlers.WithAdminRole(cfg.Auth.AdminRole))
+
+	// Create the token denylist if enabled and purge expired entries in the background
+	var routeOpts handlers.RouteOptions
+	if cfg.Auth.TokenDenylistEnabled {
+		revocationsRepo := repository.NewPostgresTokenRevocationRepository(db)
+		go repository.PurgeExpiredRevocationsPeriodically(ctx, revocationsRepo, cfg.Auth.TokenDenylistPurgeInterval)
+		routeOpts.TokenRevocations = revocationsRepo
+	}
 
 	// Setup all routes with auth configuration and context
-	handlers.SetupRoutesWithContext(ctx, eventsHandler, cfg.Auth)
+	handlers.SetupRoutesWithOptions(ctx, eventsHandler, cfg.Auth, routeOpts)
 
-	// Start the server
+	// Start the server, with TLS and client certificates if configured
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("Server starting on %s (validation method: %s)", addr, cfg.Auth.ValidationMethod)
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if !cfg.Server.TLSEnabled() {
+		log.Printf("Server starting on %s (validation method: %s)", addr, cfg.Auth.ValidationMethod)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Fatalf("Error starting server: %v", err)
+		}
+		return
+	}
+
+	tlsConfig, err := setupTLS(cfg.Server)
+	if err != nil {
+		log.Fatalf("Error setting up TLS: %v", err)
+	}
+	server := &http.Server{Addr: addr, TLSConfig: tlsConfig}
+	log.Printf("Server starting on %s with TLS (client auth: %s, validation method: %s)",
+		addr, cfg.Server.TLSClientAuth, cfg.Auth.ValidationMethod)
+	if err := server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile); err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
 }
 
+// setupTLS creates the TLS configuration for client certificate authentication
+// Certificates are verified against TLSClientCAFile if set; otherwise any certificate
+// is accepted and bound tokens are checked by thumbprint only (RFC 8705, section 2.2)
+func setupTLS(serverConfig config.ServerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	verify := serverConfig.TLSClientCAFile != ""
+	if verify {
+		caPEM, err := os.ReadFile(serverConfig.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", serverConfig.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	switch serverConfig.TLSClientAuth {
+	case "", "none":
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case "optional":
+		tlsConfig.ClientAuth = tls.RequestClientCert
+		if verify {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	case "required":
+		tlsConfig.ClientAuth = tls.RequireAnyClientCert
+		if verify {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	default:
+		return nil, fmt.Errorf("unsupported TLS client auth mode: %s", serverConfig.TLSClientAuth)
+	}
+
+	return tlsConfig, nil
+}
+
 // setupDatabase creates a connection to the PostgreSQL database
 func setupDatabase(dbConfig config.DatabaseConfig) (*sql.DB, error) {
 	// Create connection string using the configuration
@@ -0,0 +1,82 @@
+// Command fakekeycloak serves an in-process fake Keycloak realm for offline development
+//
+// It listens on the Keycloak port of the docker-compose setup, so the API server
+// runs against it unchanged:
+//
+//	go run ./cmd/fakekeycloak -user alice:secret
+//	CLIENT_SECRET=events-api-secret go run ./cmd/api
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+// users collects repeated -user flags
+type users []string
+
+func (u *users) String() string     { return strings.Join(*u, ",") }
+func (u *users) Set(v string) error { *u = append(*u, v); return nil }
+
+func main() {
+	addr := flag.String("addr", "localhost:8081", "listen address")
+	realm := flag.String("realm", oauthtest.DefaultRealm, "realm name")
+	scopes := flag.String("scopes", "events-api-access", "space-separated scopes of issued tokens")
+	roles := flag.String("roles", "", "comma-separated realm roles of issued tokens")
+	organizations := flag.String("organizations", "", "comma-separated organizations of issued tokens")
+	var logins users
+	flag.Var(&logins, "user", "username:password for the password grant (repeatable)")
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *addr, err)
+	}
+
+	server := oauthtest.NewUnstartedServer(*realm)
+	server.Listener.Close()
+	server.Listener = listener
+	// Advertise the address as given, so the issuer matches the API's KEYCLOAK_URL
+	server.BaseURL = "http://" + *addr
+
+	token := oauthtest.TokenOptions{
+		Scopes:        strings.Fields(*scopes),
+		Roles:         splitList(*roles),
+		Organizations: splitList(*organizations),
+	}
+	server.AddClient(oauthtest.DefaultClientID, oauthtest.DefaultClientSecret, token)
+	for _, login := range logins {
+		username, password, ok := strings.Cut(login, ":")
+		if !ok {
+			log.Fatalf("Invalid -user %q, want username:password", login)
+		}
+		server.AddUser(username, password, token)
+	}
+
+	server.Start()
+	defer server.Close()
+	log.Printf("Fake Keycloak realm %q listening on %s (client %s/%s)",
+		*realm, server.BaseURL, oauthtest.DefaultClientID, oauthtest.DefaultClientSecret)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
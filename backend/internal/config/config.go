@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/dotenv"
 	"github.com/knadh/koanf/providers/env"
@@ -22,6 +24,12 @@ type Config struct {
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
 	Port string `koanf:"port"`
+
+	// TLS; the server speaks plain HTTP unless TLSCertFile and TLSKeyFile are set
+	TLSCertFile     string `koanf:"tls_cert_file"`
+	TLSKeyFile      string `koanf:"tls_key_file"`
+	TLSClientAuth   string `koanf:"tls_client_auth"`    // "none" (default), "optional" or "required"
+	TLSClientCAFile string `koanf:"tls_client_ca_file"` // CA bundle for client certificates; without it any certificate is accepted and only its thumbprint matters
 }
 
 // DatabaseConfig holds database-related configuration
@@ -40,14 +48,97 @@ type AuthConfig struct {
 	ClientSecret     string `koanf:"client_secret"`
 	RequiredScope    string `koanf:"required_scope"`
 	RealmName        string `koanf:"realm_name"`
-	ValidationMethod string `koanf:"validation_method"` // "introspection" (default) or "jwks"
+	ValidationMethod string `koanf:"validation_method"` // "introspection" (default), "jwks" or "hybrid"
+
+	// Client authentication towards the token and introspection endpoints
+	ClientAuthMethod string `koanf:"client_auth_method"` // "client_secret_post" (default), "client_secret_basic" or "private_key_jwt"
+	ClientKeyFile    string `koanf:"client_key_file"`    // PEM-encoded RSA or EC private key for private_key_jwt
+	ClientKeyID      string `koanf:"client_key_id"`      // kid of the client key as registered in Keycloak
+
+	// Resilience of requests to the identity provider (introspection, token, discovery)
+	IdPTimeout                 time.Duration `koanf:"idp_timeout"`                   // per-attempt request timeout
+	IdPMaxRetries              int           `koanf:"idp_max_retries"`               // retries for transport errors and 5xx responses
+	IdPRetryBackoff            time.Duration `koanf:"idp_retry_backoff"`             // wait before the first retry, doubled for each further retry
+	IdPCircuitBreakerThreshold int           `koanf:"idp_circuit_breaker_threshold"` // consecutive failed requests that open the breaker; 0 disables it
+	IdPCircuitBreakerCooldown  time.Duration `koanf:"idp_circuit_breaker_cooldown"`  // how long the open breaker fails fast before a trial request
+
+	// OIDC discovery; without DiscoveryURL, endpoints are derived from KeycloakURL and RealmName
+	DiscoveryURL             string        `koanf:"discovery_url"`              // e.g. http://keycloak:8080/realms/events/.well-known/openid-configuration
+	DiscoveryRefreshInterval time.Duration `koanf:"discovery_refresh_interval"` // how long a fetched discovery document is used
+	ExpectedIssuer           string        `koanf:"expected_issuer"`            // overrides the discovered or derived issuer
+
+	// Multi-issuer validation; if set, tokens are routed to a validator per issuer
+	// Entries are "<issuer>" or "<issuer>|<method>", e.g. "http://localhost:8081/realms/events|jwks"
+	TrustedIssuers []string `koanf:"trusted_issuers"`
+
+	// JWT audience and authorized party checks (JWKS mode)
+	ExpectedAudiences        []string `koanf:"expected_audiences"`         // token must contain at least one of these in aud
+	AllowedAuthorizedParties []string `koanf:"allowed_authorized_parties"` // if set, azp must be one of these
+
+	// Claim mapping: JSON pointer paths into the token payload or introspection response,
+	// see oauth.ClaimMapping; empty fields use Keycloak's default locations
+	ClaimSubject       []string `koanf:"claim_subject"`       // first value found wins
+	ClaimUsername      []string `koanf:"claim_username"`      // first value found wins
+	ClaimEmail         []string `koanf:"claim_email"`         // first value found wins
+	ClaimRoles         []string `koanf:"claim_roles"`         // values of all paths are merged
+	ClaimGroups        []string `koanf:"claim_groups"`        // values of all paths are merged
+	ClaimOrganizations []string `koanf:"claim_organizations"` // values of all paths are merged, list or map form
+	ClaimAttributes    []string `koanf:"claim_attributes"`    // entries "<name>=<path>", e.g. "tenant=/ext/tenant_id"
+
+	// JWS algorithms accepted for access tokens (JWKS mode); if empty, the supported
+	// algorithms advertised by the discovery document are used, else RS256/RS384/RS512
+	AllowedAlgorithms []string `koanf:"allowed_algorithms"`
+
+	// JWK Set file of private keys for decrypting encrypted (JWE) access tokens (JWKS mode);
+	// if empty, encrypted tokens are rejected
+	TokenDecryptionKeysFile string `koanf:"token_decryption_keys_file"`
+
+	// Temporal claim checks (exp, nbf, iat), applied in every validation mode
+	ClockSkewLeeway time.Duration `koanf:"clock_skew_leeway"` // tolerated clock difference to the identity provider
+	MaxTokenAge     time.Duration `koanf:"max_token_age"`     // if positive, tokens issued longer ago are rejected
+
+	// Introspection result cache, off by default; a size of 0 disables caching
+	// While enabled, a revoked token stays accepted until its cached result expires
+	IntrospectionCacheSize        int           `koanf:"introspection_cache_size"`         // maximum number of cached tokens
+	IntrospectionCacheTTL         time.Duration `koanf:"introspection_cache_ttl"`          // upper bound for active results, never past the token's exp
+	IntrospectionCacheNegativeTTL time.Duration `koanf:"introspection_cache_negative_ttl"` // lifetime of cached inactive results
+
+	// Hybrid validation: revocation checks via introspection on top of local JWT verification
+	HybridRecheckInterval time.Duration `koanf:"hybrid_recheck_interval"` // maximum time a revoked token stays accepted
+	HybridSampleRate      float64       `koanf:"hybrid_sample_rate"`      // fraction of requests (0..1) introspected regardless of interval
+	SensitiveRoutes       []string      `koanf:"sensitive_routes"`        // route patterns, e.g. "/events/{id}", validated strictly on every method
+
+	// DPoP sender-constrained tokens (RFC 9449)
+	DPoPMode           string        `koanf:"dpop_mode"`            // "disabled" (default), "allowed" or "required"; the mode of all other routes
+	DPoPRequiredRoutes []string      `koanf:"dpop_required_routes"` // route patterns, e.g. "/events/{id}", that accept DPoP only
+	DPoPProofMaxAge    time.Duration `koanf:"dpop_proof_max_age"`   // maximum proof age, also the jti replay window
+	DPoPNonceRequired  bool          `koanf:"dpop_nonce_required"`  // require a server-issued nonce in proofs
+	PublicURL          string        `koanf:"public_url"`           // externally visible base URL, used to check the proof's htu
+
+	// OIDC Back-Channel Logout receiver at POST /backchannel-logout
+	BackchannelLogoutEnabled   bool          `koanf:"backchannel_logout_enabled"`
+	BackchannelLogoutAudiences []string      `koanf:"backchannel_logout_audiences"` // client IDs Keycloak sends logout tokens for
+	BackchannelLogoutRetention time.Duration `koanf:"backchannel_logout_retention"` // must cover the longest access token lifetime
+
+	// Persistent jti denylist for individually revoked access tokens
+	TokenDenylistEnabled       bool          `koanf:"token_denylist_enabled"`
+	TokenDenylistPurgeInterval time.Duration `koanf:"token_denylist_purge_interval"` // how often expired entries are deleted
+	AdminRole                  string        `koanf:"admin_role"`                    // realm role required for /admin endpoints
+
+	// Keycloak Authorization Services (UMA) policy enforcement; replaces RequiredScope checks when enabled
+	PolicyEnforcerEnabled   bool          `koanf:"policy_enforcer_enabled"`
+	PolicyResource          string        `koanf:"policy_resource"`            // resource name registered on the ClientID resource server
+	PolicyPermissions       []string      `koanf:"policy_permissions"`         // "<METHOD> <pattern>=<resource>#<scope>" entries, replacing the PolicyResource defaults per route
+	PolicyDecisionCacheSize int           `koanf:"policy_decision_cache_size"` // maximum number of cached decisions
+	PolicyDecisionCacheTTL  time.Duration `koanf:"policy_decision_cache_ttl"`  // decision lifetime, never past the token's exp
 }
 
 // DefaultConfig returns a Config with default values
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: "8080",
+			Port:          "8080",
+			TLSClientAuth: "none",
 		},
 		Database: DatabaseConfig{
 			Host:     "postgres",
@@ -62,6 +153,38 @@ func DefaultConfig() *Config {
 			RequiredScope:    "events-api-access",
 			RealmName:        "events",
 			ValidationMethod: "introspection",
+			ClientAuthMethod: "client_secret_post",
+
+			IdPTimeout:                 5 * time.Second,
+			IdPMaxRetries:              2,
+			IdPRetryBackoff:            100 * time.Millisecond,
+			IdPCircuitBreakerThreshold: 5,
+			IdPCircuitBreakerCooldown:  30 * time.Second,
+
+			DiscoveryRefreshInterval: time.Hour,
+
+			ExpectedAudiences: []string{"events-api"},
+
+			ClockSkewLeeway: 30 * time.Second,
+
+			IntrospectionCacheSize:        0,
+			IntrospectionCacheTTL:         time.Minute,
+			IntrospectionCacheNegativeTTL: 10 * time.Second,
+
+			HybridRecheckInterval: 30 * time.Second,
+
+			DPoPMode:        "disabled",
+			DPoPProofMaxAge: time.Minute,
+
+			BackchannelLogoutAudiences: []string{"events-frontend"},
+			BackchannelLogoutRetention: time.Hour,
+
+			TokenDenylistPurgeInterval: 10 * time.Minute,
+			AdminRole:                  "system-admin",
+
+			PolicyResource:          "api.schneefisch.oauth-keycloak-demo.events",
+			PolicyDecisionCacheSize: 1000,
+			PolicyDecisionCacheTTL:  time.Minute,
 		},
 	}
 }
@@ -101,6 +224,21 @@ func Load(configFile string) (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling auth config: %w", err)
 	}
 
+	// Special handling for SERVER_TLS_* environment variables
+	// The generic loader would map SERVER_TLS_CERT_FILE to server.tls.cert.file
+	if certFile := os.Getenv("SERVER_TLS_CERT_FILE"); certFile != "" {
+		cfg.Server.TLSCertFile = certFile
+	}
+	if keyFile := os.Getenv("SERVER_TLS_KEY_FILE"); keyFile != "" {
+		cfg.Server.TLSKeyFile = keyFile
+	}
+	if clientAuth := os.Getenv("SERVER_TLS_CLIENT_AUTH"); clientAuth != "" {
+		cfg.Server.TLSClientAuth = clientAuth
+	}
+	if clientCAFile := os.Getenv("SERVER_TLS_CLIENT_CA_FILE"); clientCAFile != "" {
+		cfg.Server.TLSClientCAFile = clientCAFile
+	}
+
 	// Special handling for CLIENT_SECRET environment variable
 	// This is needed because the environment variable name doesn't match the expected format
 	if clientSecret := os.Getenv("CLIENT_SECRET"); clientSecret != "" {
@@ -131,9 +269,234 @@ func Load(configFile string) (*Config, error) {
 		cfg.Auth.ValidationMethod = validationMethod
 	}
 
+	// Special handling for client authentication environment variables
+	// With private_key_jwt, CLIENT_SECRET is not needed
+	if clientAuthMethod := os.Getenv("CLIENT_AUTH_METHOD"); clientAuthMethod != "" {
+		cfg.Auth.ClientAuthMethod = clientAuthMethod
+	}
+	if clientKeyFile := os.Getenv("CLIENT_KEY_FILE"); clientKeyFile != "" {
+		cfg.Auth.ClientKeyFile = clientKeyFile
+	}
+	if clientKeyID := os.Getenv("CLIENT_KEY_ID"); clientKeyID != "" {
+		cfg.Auth.ClientKeyID = clientKeyID
+	}
+
+	// Identity provider resilience settings
+	if err := lookupEnvDuration("IDP_TIMEOUT", &cfg.Auth.IdPTimeout); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvInt("IDP_MAX_RETRIES", &cfg.Auth.IdPMaxRetries); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("IDP_RETRY_BACKOFF", &cfg.Auth.IdPRetryBackoff); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvInt("IDP_CIRCUIT_BREAKER_THRESHOLD", &cfg.Auth.IdPCircuitBreakerThreshold); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("IDP_CIRCUIT_BREAKER_COOLDOWN", &cfg.Auth.IdPCircuitBreakerCooldown); err != nil {
+		return nil, err
+	}
+
+	// Special handling for OIDC discovery environment variables
+	// EXPECTED_ISSUER is needed when the backend reaches Keycloak under a different
+	// hostname than the one in the tokens, e.g. keycloak:8080 vs localhost:8081 in Docker
+	if discoveryURL := os.Getenv("OIDC_DISCOVERY_URL"); discoveryURL != "" {
+		cfg.Auth.DiscoveryURL = discoveryURL
+	}
+	if err := lookupEnvDuration("OIDC_DISCOVERY_REFRESH_INTERVAL", &cfg.Auth.DiscoveryRefreshInterval); err != nil {
+		return nil, err
+	}
+	if expectedIssuer := os.Getenv("EXPECTED_ISSUER"); expectedIssuer != "" {
+		cfg.Auth.ExpectedIssuer = expectedIssuer
+	}
+
+	// Special handling for TRUSTED_ISSUERS environment variable
+	// Comma-separated list of "<issuer>" or "<issuer>|<method>" entries
+	lookupEnvList("TRUSTED_ISSUERS", &cfg.Auth.TrustedIssuers)
+
+	// Special handling for EXPECTED_AUDIENCES and ALLOWED_AUTHORIZED_PARTIES environment variables
+	// Both are comma-separated lists, e.g. "events-api,reporting-api"
+	lookupEnvList("EXPECTED_AUDIENCES", &cfg.Auth.ExpectedAudiences)
+	lookupEnvList("ALLOWED_AUTHORIZED_PARTIES", &cfg.Auth.AllowedAuthorizedParties)
+
+	// Special handling for the CLAIM_* environment variables, comma-separated lists of paths,
+	// e.g. CLAIM_ROLES="/realm_access/roles,/resource_access/*/roles,/ext/roles"
+	lookupEnvList("CLAIM_SUBJECT", &cfg.Auth.ClaimSubject)
+	lookupEnvList("CLAIM_USERNAME", &cfg.Auth.ClaimUsername)
+	lookupEnvList("CLAIM_EMAIL", &cfg.Auth.ClaimEmail)
+	lookupEnvList("CLAIM_ROLES", &cfg.Auth.ClaimRoles)
+	lookupEnvList("CLAIM_GROUPS", &cfg.Auth.ClaimGroups)
+	lookupEnvList("CLAIM_ORGANIZATIONS", &cfg.Auth.ClaimOrganizations)
+	lookupEnvList("CLAIM_ATTRIBUTES", &cfg.Auth.ClaimAttributes)
+
+	// Special handling for ALLOWED_ALGORITHMS environment variable, e.g. "ES256,RS256"
+	lookupEnvList("ALLOWED_ALGORITHMS", &cfg.Auth.AllowedAlgorithms)
+
+	// Special handling for TOKEN_DECRYPTION_KEYS_FILE environment variable
+	if keysFile := os.Getenv("TOKEN_DECRYPTION_KEYS_FILE"); keysFile != "" {
+		cfg.Auth.TokenDecryptionKeysFile = keysFile
+	}
+
+	// Special handling for CLOCK_SKEW_LEEWAY and MAX_TOKEN_AGE environment variables
+	if err := lookupEnvDuration("CLOCK_SKEW_LEEWAY", &cfg.Auth.ClockSkewLeeway); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("MAX_TOKEN_AGE", &cfg.Auth.MaxTokenAge); err != nil {
+		return nil, err
+	}
+
+	// Introspection cache settings
+	if err := lookupEnvInt("INTROSPECTION_CACHE_SIZE", &cfg.Auth.IntrospectionCacheSize); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("INTROSPECTION_CACHE_TTL", &cfg.Auth.IntrospectionCacheTTL); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("INTROSPECTION_CACHE_NEGATIVE_TTL", &cfg.Auth.IntrospectionCacheNegativeTTL); err != nil {
+		return nil, err
+	}
+
+	// Hybrid validation settings
+	if err := lookupEnvDuration("HYBRID_RECHECK_INTERVAL", &cfg.Auth.HybridRecheckInterval); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvFloat("HYBRID_SAMPLE_RATE", &cfg.Auth.HybridSampleRate); err != nil {
+		return nil, err
+	}
+	lookupEnvList("SENSITIVE_ROUTES", &cfg.Auth.SensitiveRoutes)
+
+	// DPoP settings
+	if dpopMode := os.Getenv("DPOP_MODE"); dpopMode != "" {
+		cfg.Auth.DPoPMode = dpopMode
+	}
+	lookupEnvList("DPOP_REQUIRED_ROUTES", &cfg.Auth.DPoPRequiredRoutes)
+	if err := lookupEnvDuration("DPOP_PROOF_MAX_AGE", &cfg.Auth.DPoPProofMaxAge); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvBool("DPOP_NONCE_REQUIRED", &cfg.Auth.DPoPNonceRequired); err != nil {
+		return nil, err
+	}
+	if publicURL := os.Getenv("PUBLIC_URL"); publicURL != "" {
+		cfg.Auth.PublicURL = publicURL
+	}
+
+	// Back-channel logout settings
+	if err := lookupEnvBool("BACKCHANNEL_LOGOUT_ENABLED", &cfg.Auth.BackchannelLogoutEnabled); err != nil {
+		return nil, err
+	}
+	lookupEnvList("BACKCHANNEL_LOGOUT_AUDIENCES", &cfg.Auth.BackchannelLogoutAudiences)
+	if err := lookupEnvDuration("BACKCHANNEL_LOGOUT_RETENTION", &cfg.Auth.BackchannelLogoutRetention); err != nil {
+		return nil, err
+	}
+
+	// Token denylist settings
+	if err := lookupEnvBool("TOKEN_DENYLIST_ENABLED", &cfg.Auth.TokenDenylistEnabled); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("TOKEN_DENYLIST_PURGE_INTERVAL", &cfg.Auth.TokenDenylistPurgeInterval); err != nil {
+		return nil, err
+	}
+	if adminRole := os.Getenv("ADMIN_ROLE"); adminRole != "" {
+		cfg.Auth.AdminRole = adminRole
+	}
+
+	// Policy enforcer settings
+	if err := lookupEnvBool("POLICY_ENFORCER_ENABLED", &cfg.Auth.PolicyEnforcerEnabled); err != nil {
+		return nil, err
+	}
+	if policyResource := os.Getenv("POLICY_RESOURCE"); policyResource != "" {
+		cfg.Auth.PolicyResource = policyResource
+	}
+	lookupEnvList("POLICY_PERMISSIONS", &cfg.Auth.PolicyPermissions)
+	if err := lookupEnvInt("POLICY_DECISION_CACHE_SIZE", &cfg.Auth.PolicyDecisionCacheSize); err != nil {
+		return nil, err
+	}
+	if err := lookupEnvDuration("POLICY_DECISION_CACHE_TTL", &cfg.Auth.PolicyDecisionCacheTTL); err != nil {
+		return nil, err
+	}
+
+	// The denylist purge runs on a ticker, which requires a positive interval
+	if cfg.Auth.TokenDenylistPurgeInterval <= 0 {
+		return nil, fmt.Errorf("invalid value for TOKEN_DENYLIST_PURGE_INTERVAL: %s, must be positive", cfg.Auth.TokenDenylistPurgeInterval)
+	}
+
 	return cfg, nil
 }
 
+// lookupEnvList sets target from the named comma-separated environment variable if it is set
+// Empty items are dropped and surrounding whitespace is trimmed
+func lookupEnvList(name string, target *[]string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+// lookupEnvBool sets target from the named environment variable if it is set
+func lookupEnvBool(name string, target *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	*target = parsed
+	return nil
+}
+
+// lookupEnvInt sets target from the named environment variable if it is set
+func lookupEnvInt(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	*target = parsed
+	return nil
+}
+
+// lookupEnvFloat sets target from the named environment variable if it is set
+func lookupEnvFloat(name string, target *float64) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	*target = parsed
+	return nil
+}
+
+// lookupEnvDuration sets target from the named environment variable if it is set
+// Values use Go duration syntax, e.g. "30s" or "5m"
+func lookupEnvDuration(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	*target = parsed
+	return nil
+}
+
 // TestConfig creates a configuration for testing with the given overrides
 func TestConfig(overrides *Config) *Config {
 	cfg := DefaultConfig()
@@ -144,6 +507,18 @@ func TestConfig(overrides *Config) *Config {
 		if overrides.Server.Port != "" {
 			cfg.Server.Port = overrides.Server.Port
 		}
+		if overrides.Server.TLSCertFile != "" {
+			cfg.Server.TLSCertFile = overrides.Server.TLSCertFile
+		}
+		if overrides.Server.TLSKeyFile != "" {
+			cfg.Server.TLSKeyFile = overrides.Server.TLSKeyFile
+		}
+		if overrides.Server.TLSClientAuth != "" {
+			cfg.Server.TLSClientAuth = overrides.Server.TLSClientAuth
+		}
+		if overrides.Server.TLSClientCAFile != "" {
+			cfg.Server.TLSClientCAFile = overrides.Server.TLSClientCAFile
+		}
 
 		// Database overrides
 		if overrides.Database.Host != "" {
@@ -181,6 +556,147 @@ func TestConfig(overrides *Config) *Config {
 		if overrides.Auth.ValidationMethod != "" {
 			cfg.Auth.ValidationMethod = overrides.Auth.ValidationMethod
 		}
+		if overrides.Auth.ClientAuthMethod != "" {
+			cfg.Auth.ClientAuthMethod = overrides.Auth.ClientAuthMethod
+		}
+		if overrides.Auth.ClientKeyFile != "" {
+			cfg.Auth.ClientKeyFile = overrides.Auth.ClientKeyFile
+		}
+		if overrides.Auth.ClientKeyID != "" {
+			cfg.Auth.ClientKeyID = overrides.Auth.ClientKeyID
+		}
+		if overrides.Auth.IdPTimeout != 0 {
+			cfg.Auth.IdPTimeout = overrides.Auth.IdPTimeout
+		}
+		if overrides.Auth.IdPMaxRetries != 0 {
+			cfg.Auth.IdPMaxRetries = overrides.Auth.IdPMaxRetries
+		}
+		if overrides.Auth.IdPRetryBackoff != 0 {
+			cfg.Auth.IdPRetryBackoff = overrides.Auth.IdPRetryBackoff
+		}
+		if overrides.Auth.IdPCircuitBreakerThreshold != 0 {
+			cfg.Auth.IdPCircuitBreakerThreshold = overrides.Auth.IdPCircuitBreakerThreshold
+		}
+		if overrides.Auth.IdPCircuitBreakerCooldown != 0 {
+			cfg.Auth.IdPCircuitBreakerCooldown = overrides.Auth.IdPCircuitBreakerCooldown
+		}
+		if overrides.Auth.DiscoveryURL != "" {
+			cfg.Auth.DiscoveryURL = overrides.Auth.DiscoveryURL
+		}
+		if overrides.Auth.DiscoveryRefreshInterval != 0 {
+			cfg.Auth.DiscoveryRefreshInterval = overrides.Auth.DiscoveryRefreshInterval
+		}
+		if overrides.Auth.ExpectedIssuer != "" {
+			cfg.Auth.ExpectedIssuer = overrides.Auth.ExpectedIssuer
+		}
+		if len(overrides.Auth.TrustedIssuers) > 0 {
+			cfg.Auth.TrustedIssuers = overrides.Auth.TrustedIssuers
+		}
+		if len(overrides.Auth.ExpectedAudiences) > 0 {
+			cfg.Auth.ExpectedAudiences = overrides.Auth.ExpectedAudiences
+		}
+		if len(overrides.Auth.AllowedAuthorizedParties) > 0 {
+			cfg.Auth.AllowedAuthorizedParties = overrides.Auth.AllowedAuthorizedParties
+		}
+		if len(overrides.Auth.ClaimSubject) > 0 {
+			cfg.Auth.ClaimSubject = overrides.Auth.ClaimSubject
+		}
+		if len(overrides.Auth.ClaimUsername) > 0 {
+			cfg.Auth.ClaimUsername = overrides.Auth.ClaimUsername
+		}
+		if len(overrides.Auth.ClaimEmail) > 0 {
+			cfg.Auth.ClaimEmail = overrides.Auth.ClaimEmail
+		}
+		if len(overrides.Auth.ClaimRoles) > 0 {
+			cfg.Auth.ClaimRoles = overrides.Auth.ClaimRoles
+		}
+		if len(overrides.Auth.ClaimGroups) > 0 {
+			cfg.Auth.ClaimGroups = overrides.Auth.ClaimGroups
+		}
+		if len(overrides.Auth.ClaimOrganizations) > 0 {
+			cfg.Auth.ClaimOrganizations = overrides.Auth.ClaimOrganizations
+		}
+		if len(overrides.Auth.ClaimAttributes) > 0 {
+			cfg.Auth.ClaimAttributes = overrides.Auth.ClaimAttributes
+		}
+		if len(overrides.Auth.AllowedAlgorithms) > 0 {
+			cfg.Auth.AllowedAlgorithms = overrides.Auth.AllowedAlgorithms
+		}
+		if overrides.Auth.TokenDecryptionKeysFile != "" {
+			cfg.Auth.TokenDecryptionKeysFile = overrides.Auth.TokenDecryptionKeysFile
+		}
+		if overrides.Auth.ClockSkewLeeway != 0 {
+			cfg.Auth.ClockSkewLeeway = overrides.Auth.ClockSkewLeeway
+		}
+		if overrides.Auth.MaxTokenAge != 0 {
+			cfg.Auth.MaxTokenAge = overrides.Auth.MaxTokenAge
+		}
+		if overrides.Auth.IntrospectionCacheSize != 0 {
+			cfg.Auth.IntrospectionCacheSize = overrides.Auth.IntrospectionCacheSize
+		}
+		if overrides.Auth.IntrospectionCacheTTL != 0 {
+			cfg.Auth.IntrospectionCacheTTL = overrides.Auth.IntrospectionCacheTTL
+		}
+		if overrides.Auth.IntrospectionCacheNegativeTTL != 0 {
+			cfg.Auth.IntrospectionCacheNegativeTTL = overrides.Auth.IntrospectionCacheNegativeTTL
+		}
+		if overrides.Auth.HybridRecheckInterval != 0 {
+			cfg.Auth.HybridRecheckInterval = overrides.Auth.HybridRecheckInterval
+		}
+		if overrides.Auth.HybridSampleRate != 0 {
+			cfg.Auth.HybridSampleRate = overrides.Auth.HybridSampleRate
+		}
+		if len(overrides.Auth.SensitiveRoutes) > 0 {
+			cfg.Auth.SensitiveRoutes = overrides.Auth.SensitiveRoutes
+		}
+		if overrides.Auth.DPoPMode != "" {
+			cfg.Auth.DPoPMode = overrides.Auth.DPoPMode
+		}
+		if len(overrides.Auth.DPoPRequiredRoutes) > 0 {
+			cfg.Auth.DPoPRequiredRoutes = overrides.Auth.DPoPRequiredRoutes
+		}
+		if overrides.Auth.DPoPProofMaxAge != 0 {
+			cfg.Auth.DPoPProofMaxAge = overrides.Auth.DPoPProofMaxAge
+		}
+		if overrides.Auth.DPoPNonceRequired {
+			cfg.Auth.DPoPNonceRequired = true
+		}
+		if overrides.Auth.PublicURL != "" {
+			cfg.Auth.PublicURL = overrides.Auth.PublicURL
+		}
+		if overrides.Auth.BackchannelLogoutEnabled {
+			cfg.Auth.BackchannelLogoutEnabled = true
+		}
+		if len(overrides.Auth.BackchannelLogoutAudiences) > 0 {
+			cfg.Auth.BackchannelLogoutAudiences = overrides.Auth.BackchannelLogoutAudiences
+		}
+		if overrides.Auth.BackchannelLogoutRetention != 0 {
+			cfg.Auth.BackchannelLogoutRetention = overrides.Auth.BackchannelLogoutRetention
+		}
+		if overrides.Auth.TokenDenylistEnabled {
+			cfg.Auth.TokenDenylistEnabled = true
+		}
+		if overrides.Auth.TokenDenylistPurgeInterval != 0 {
+			cfg.Auth.TokenDenylistPurgeInterval = overrides.Auth.TokenDenylistPurgeInterval
+		}
+		if overrides.Auth.AdminRole != "" {
+			cfg.Auth.AdminRole = overrides.Auth.AdminRole
+		}
+		if overrides.Auth.PolicyEnforcerEnabled {
+			cfg.Auth.PolicyEnforcerEnabled = true
+		}
+		if overrides.Auth.PolicyResource != "" {
+			cfg.Auth.PolicyResource = overrides.Auth.PolicyResource
+		}
+		if len(overrides.Auth.PolicyPermissions) > 0 {
+			cfg.Auth.PolicyPermissions = overrides.Auth.PolicyPermissions
+		}
+		if overrides.Auth.PolicyDecisionCacheSize != 0 {
+			cfg.Auth.PolicyDecisionCacheSize = overrides.Auth.PolicyDecisionCacheSize
+		}
+		if overrides.Auth.PolicyDecisionCacheTTL != 0 {
+			cfg.Auth.PolicyDecisionCacheTTL = overrides.Auth.PolicyDecisionCacheTTL
+		}
 	}
 
 	return cfg
@@ -191,3 +707,8 @@ func (c *DatabaseConfig) ConnectionString() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		c.Host, c.Port, c.User, c.Password, c.Name)
 }
+
+// TLSEnabled reports whether the server is configured to serve HTTPS
+func (c *ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad_TokenDenylistPurgeInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "Default", want: 10 * time.Minute},
+		{name: "Configured", value: "30s", want: 30 * time.Second},
+		{name: "Zero", value: "0s", wantErr: true},
+		{name: "Negative", value: "-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TOKEN_DENYLIST_PURGE_INTERVAL", tt.value)
+
+			cfg, err := Load("")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.Auth.TokenDenylistPurgeInterval != tt.want {
+				t.Errorf("TokenDenylistPurgeInterval = %v, want %v", cfg.Auth.TokenDenylistPurgeInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_IntrospectionCacheSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{name: "Default", want: 0},
+		{name: "Configured", value: "500", want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INTROSPECTION_CACHE_SIZE", tt.value)
+
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Auth.IntrospectionCacheSize != tt.want {
+				t.Errorf("IntrospectionCacheSize = %v, want %v", cfg.Auth.IntrospectionCacheSize, tt.want)
+			}
+		})
+	}
+}
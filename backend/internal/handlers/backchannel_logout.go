@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+)
+
+// maxLogoutPayloadBytes limits the size of the form posted by Keycloak, which holds a
+// single signed logout token
+const maxLogoutPayloadBytes = 16 << 10
+
+// LogoutTokenValidator validates OIDC Back-Channel Logout tokens
+type LogoutTokenValidator interface {
+	ValidateLogoutToken(token string) (*oauth.LogoutToken, error)
+}
+
+// logoutErrorResponse is the JSON body returned when a logout request is rejected
+type logoutErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// BackchannelLogoutHandler receives logout tokens sent by Keycloak when a user session ends
+type BackchannelLogoutHandler struct {
+	validator   LogoutTokenValidator
+	revocations *oauth.SessionRevocations
+}
+
+// NewBackchannelLogoutHandler creates a new BackchannelLogoutHandler
+func NewBackchannelLogoutHandler(validator LogoutTokenValidator, revocations *oauth.SessionRevocations) *BackchannelLogoutHandler {
+	return &BackchannelLogoutHandler{validator: validator, revocations: revocations}
+}
+
+// Logout validates the logout_token form parameter and revokes the session or subject it names
+func (h *BackchannelLogoutHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxLogoutPayloadBytes)
+	logoutToken := r.PostFormValue("logout_token")
+	if logoutToken == "" {
+		writeJSON(w, http.StatusBadRequest, logoutErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "missing logout_token",
+		})
+		return
+	}
+
+	logout, err := h.validator.ValidateLogoutToken(logoutToken)
+	if err != nil {
+		log.Printf("Back-channel logout rejected: %v", err)
+		writeJSON(w, http.StatusBadRequest, logoutErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "invalid logout_token",
+		})
+		return
+	}
+
+	h.revocations.Revoke(logout)
+	log.Printf("Back-channel logout: revoked session %q of subject %q", logout.SessionID, logout.Subject)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+)
+
+// MockLogoutTokenValidator is a mock implementation of LogoutTokenValidator
+type MockLogoutTokenValidator struct {
+	ValidateLogoutTokenFunc func(token string) (*oauth.LogoutToken, error)
+}
+
+func (m *MockLogoutTokenValidator) ValidateLogoutToken(token string) (*oauth.LogoutToken, error) {
+	return m.ValidateLogoutTokenFunc(token)
+}
+
+func TestBackchannelLogout(t *testing.T) {
+	loggedOutAt := time.Now()
+	validator := &MockLogoutTokenValidator{
+		ValidateLogoutTokenFunc: func(token string) (*oauth.LogoutToken, error) {
+			if token != "valid-logout-token" {
+				return nil, oauth.ErrInvalidLogoutToken
+			}
+			return &oauth.LogoutToken{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt}, nil
+		},
+	}
+
+	tests := []struct {
+		name           string
+		logoutToken    string
+		expectedStatus int
+		expectRevoked  bool
+	}{
+		{
+			name:           "Valid logout token",
+			logoutToken:    "valid-logout-token",
+			expectedStatus: http.StatusOK,
+			expectRevoked:  true,
+		},
+		{
+			name:           "Invalid logout token",
+			logoutToken:    "forged-logout-token",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing logout token",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations := oauth.NewSessionRevocations(time.Hour)
+			handler := NewBackchannelLogoutHandler(validator, revocations)
+
+			form := url.Values{}
+			if tt.logoutToken != "" {
+				form.Set("logout_token", tt.logoutToken)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/backchannel-logout", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			handler.Logout(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+
+			revoked := revocations.IsRevoked(&oauth.AuthClaims{
+				Subject:   "user-123",
+				SessionID: "session-1",
+				IssuedAt:  loggedOutAt.Add(-time.Minute),
+			})
+			if revoked != tt.expectRevoked {
+				t.Errorf("session revoked = %v, want %v", revoked, tt.expectRevoked)
+			}
+		})
+	}
+}
+
+func TestBackchannelLogout_OversizedForm(t *testing.T) {
+	validator := &MockLogoutTokenValidator{
+		ValidateLogoutTokenFunc: func(token string) (*oauth.LogoutToken, error) {
+			return &oauth.LogoutToken{Subject: "user-123", SessionID: "session-1", IssuedAt: time.Now()}, nil
+		},
+	}
+	revocations := oauth.NewSessionRevocations(time.Hour)
+	handler := NewBackchannelLogoutHandler(validator, revocations)
+
+	form := url.Values{"logout_token": {"valid-logout-token"}, "padding": {strings.Repeat("a", maxLogoutPayloadBytes)}}
+	req := httptest.NewRequest(http.MethodPost, "/backchannel-logout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handler.Logout(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if revocations.IsRevoked(&oauth.AuthClaims{Subject: "user-123", SessionID: "session-1"}) {
+		t.Error("session revoked despite oversized form")
+	}
+}
+
+func TestBackchannelLogout_ValidatorError(t *testing.T) {
+	validator := &MockLogoutTokenValidator{
+		ValidateLogoutTokenFunc: func(token string) (*oauth.LogoutToken, error) {
+			return nil, errors.New("jwks unavailable")
+		},
+	}
+	handler := NewBackchannelLogoutHandler(validator, oauth.NewSessionRevocations(time.Hour))
+
+	req := httptest.NewRequest(http.MethodPost, "/backchannel-logout", strings.NewReader("logout_token=abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	handler.Logout(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "invalid_request") {
+		t.Errorf("body = %q, want invalid_request error", rr.Body.String())
+	}
+}
@@ -1,23 +1,69 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/repository"
 )
 
+// maxEventPayloadBytes limits the size of create/update request bodies
+const maxEventPayloadBytes = 1 << 20
+
+// eventPayload is the request body accepted by CreateEvent and UpdateEvent
+// Date is kept as a string so that a malformed value can be reported as a field error
+type eventPayload struct {
+	Date        string `json:"date"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+}
+
+// validationErrorResponse is the JSON body returned when a payload fails validation
+type validationErrorResponse struct {
+	Error  string                  `json:"error"`
+	Fields models.ValidationErrors `json:"fields"`
+}
+
+// defaultAdminRole is the realm role that may access the events of any organization
+const defaultAdminRole = "system-admin"
+
 // EventsHandler handles HTTP requests related to events
+// Every request is scoped to the caller's Keycloak organizations, see tenant
 type EventsHandler struct {
-	repo repository.EventsRepository
+	repo      repository.EventsRepository
+	adminRole string
+}
+
+// EventsHandlerOption configures an EventsHandler
+type EventsHandlerOption func(*EventsHandler)
+
+// WithAdminRole sets the realm role that may access the events of any organization,
+// typically AuthConfig.AdminRole; an empty role keeps defaultAdminRole
+func WithAdminRole(role string) EventsHandlerOption {
+	return func(h *EventsHandler) {
+		if role != "" {
+			h.adminRole = role
+		}
+	}
 }
 
 // NewEventsHandler creates a new EventsHandler
-func NewEventsHandler(repo repository.EventsRepository) *EventsHandler {
-	return &EventsHandler{
-		repo: repo,
+func NewEventsHandler(repo repository.EventsRepository, opts ...EventsHandlerOption) *EventsHandler {
+	h := &EventsHandler{
+		repo:      repo,
+		adminRole: defaultAdminRole,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // GetEvents returns a list of events from the repository
@@ -28,8 +74,13 @@ func (h *EventsHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tenant, ok := h.tenant(w, r)
+	if !ok {
+		return
+	}
+
 	// Get events from repository
-	events, err := h.repo.GetEvents(context.Background())
+	events, err := h.repo.GetEvents(r.Context(), tenant)
 	if err != nil {
 		http.Error(w, "Error retrieving events", http.StatusInternalServerError)
 		return
@@ -60,14 +111,19 @@ func (h *EventsHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tenant, ok := h.tenant(w, r)
+	if !ok {
+		return
+	}
+
 	// Get the event from the repository
-	event, err := h.repo.GetEventByID(context.Background(), id)
+	event, err := h.repo.GetEventByID(r.Context(), tenant, id)
 	if err != nil {
 		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
 		return
 	}
 
-	// If event is nil, it means it wasn't found
+	// If event is nil, it wasn't found or belongs to another organization
 	if event == nil {
 		http.Error(w, "Event not found", http.StatusNotFound)
 		return
@@ -82,3 +138,211 @@ func (h *EventsHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// CreateEvent validates the request body and stores a new event
+func (h *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenant, ok := h.tenant(w, r)
+	if !ok {
+		return
+	}
+
+	// The event belongs to a single organization, which must be named if the
+	// caller is a member of several
+	if len(tenant.Organizations) != 1 {
+		http.Error(w, "Organization is required", http.StatusBadRequest)
+		return
+	}
+
+	// Decode and validate the payload
+	event, ok := decodeEventPayload(w, r)
+	if !ok {
+		return
+	}
+
+	// Server assigns the ID and organization
+	event.ID = uuid.New().String()
+	event.Organization = tenant.Organizations[0]
+
+	// Store the event in the repository
+	if err := h.repo.CreateEvent(r.Context(), tenant, event); err != nil {
+		http.Error(w, "Error creating event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/events/"+event.ID)
+	writeJSON(w, http.StatusCreated, event)
+}
+
+// UpdateEvent validates the request body and replaces an existing event
+func (h *EventsHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	// Only allow PUT method
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract the event ID from the URL path
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := h.tenant(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode and validate the payload
+	event, ok := decodeEventPayload(w, r)
+	if !ok {
+		return
+	}
+	event.ID = id
+
+	// Update the event in the repository
+	updated, err := h.repo.UpdateEvent(r.Context(), tenant, event)
+	if err != nil {
+		http.Error(w, "Error updating event", http.StatusInternalServerError)
+		return
+	}
+
+	// If updated is nil, the event didn't exist or belongs to another organization
+	if updated == nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteEvent removes an existing event
+func (h *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE method
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract the event ID from the URL path
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	tenant, ok := h.tenant(w, r)
+	if !ok {
+		return
+	}
+
+	// Delete the event from the repository
+	deleted, err := h.repo.DeleteEvent(r.Context(), tenant, id)
+	if err != nil {
+		http.Error(w, "Error deleting event", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tenant resolves the organizations the request may access from the caller's AuthClaims
+// Members are scoped to their organizations, or to one of them named by ?organization=
+// (alias or id); callers with the admin role may name any organization alias
+// On failure it writes the error response and returns false
+func (h *EventsHandler) tenant(w http.ResponseWriter, r *http.Request) (repository.Tenant, bool) {
+	claims := oauth.GetAuthClaims(r)
+	if claims == nil {
+		claims = &oauth.AuthClaims{}
+	}
+
+	tenant := repository.Tenant{
+		Subject: claims.Subject,
+		Admin:   h.adminRole != "" && claims.HasRole(h.adminRole),
+	}
+
+	if requested := r.URL.Query().Get("organization"); requested != "" {
+		if org, ok := claims.Organization(requested); ok {
+			tenant.Organizations = []string{org.Alias}
+			return tenant, true
+		}
+		if tenant.Admin {
+			tenant.Organizations = []string{requested}
+			return tenant, true
+		}
+		// Do not disclose whether the organization exists
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return repository.Tenant{}, false
+	}
+
+	tenant.Organizations = claims.OrganizationAliases()
+	if len(tenant.Organizations) == 0 {
+		http.Error(w, "No organization membership", http.StatusForbidden)
+		return repository.Tenant{}, false
+	}
+	return tenant, true
+}
+
+// decodeEventPayload reads the request body into an Event and validates it
+// On failure it writes the error response and returns false
+func decodeEventPayload(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	var payload eventPayload
+	r.Body = http.MaxBytesReader(w, r.Body, maxEventPayloadBytes)
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	event := &models.Event{
+		Title:       payload.Title,
+		Description: payload.Description,
+		Location:    payload.Location,
+	}
+
+	// Parse the date separately so a malformed value becomes a field error
+	var dateErr string
+	if strings.TrimSpace(payload.Date) != "" {
+		date, err := time.Parse(time.RFC3339, payload.Date)
+		if err != nil {
+			dateErr = "date must be an RFC 3339 timestamp"
+		}
+		event.Date = date
+	}
+
+	errs := event.Validate()
+	if dateErr != "" {
+		if errs == nil {
+			errs = models.ValidationErrors{}
+		}
+		errs["date"] = dateErr
+	}
+	if errs != nil {
+		writeJSON(w, http.StatusBadRequest, validationErrorResponse{
+			Error:  "validation failed",
+			Fields: errs,
+		})
+		return nil, false
+	}
+
+	return event, true
+}
+
+// writeJSON encodes the value as JSON with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
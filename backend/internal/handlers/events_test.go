@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strings"
 	"testing"
 
@@ -36,27 +38,34 @@ func createMockAuthConfig() config.AuthConfig {
 	}
 }
 
+// testOrganization is the organization of the events seeded by MockEventsRepository
+const testOrganization = "org-123"
+
 // createMockHTTPClient creates a mock HTTP client that returns a valid token response
+// for a member of testOrganization
 func createMockHTTPClient() *MockHTTPClient {
 	return &MockHTTPClient{
 		DoFunc: func(req *http.Request) (*http.Response, error) {
 			// Check if this is a token introspection request
 			if strings.Contains(req.URL.Path, "token/introspect") {
 				// Return a valid token response
-				response := oauth.TokenIntrospectionResponse{
+				response := struct {
+					oauth.TokenIntrospectionResponse
+					Organization []string `json:"organization"`
+				}{oauth.TokenIntrospectionResponse{
 					Active:    true,
 					Scope:     "test-scope",
 					ClientID:  "test-client",
 					Username:  "test-user",
 					TokenType: "Bearer",
-					Exp:       1735689600, // Some future timestamp
+					Exp:       4102444800, // Some future timestamp
 					Iat:       1619712000, // Some past timestamp
 					Nbf:       1619712000, // Some past timestamp
 					Sub:       "test-subject",
 					Aud:       []string{"test-audience"},
 					Iss:       "test-issuer",
 					Jti:       "test-jti",
-				}
+				}, []string{testOrganization}}
 
 				responseBody, _ := json.Marshal(response)
 				return &http.Response{
@@ -421,3 +430,335 @@ func TestGetEventByIDMethodNotAllowed(t *testing.T) {
 		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusMethodNotAllowed)
 	}
 }
+
+// newEventRequest creates a request of a member of testOrganization with a JSON body
+// and the given path value
+func newEventRequest(t *testing.T, method, id, body string) *http.Request {
+	t.Helper()
+	target := "/events"
+	if id != "" {
+		target += "/" + id
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if id != "" {
+		req.SetPathValue("id", id)
+	}
+	return oauth.SetAuthClaims(req, &oauth.AuthClaims{
+		Organizations: []oauth.Organization{{Alias: testOrganization}},
+	})
+}
+
+// Test creating a valid event
+func TestCreateEvent(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+	handler := NewEventsHandler(mockRepo)
+
+	body := `{"date":"2030-06-01T18:00:00Z","title":"Summer Cup","description":"Youth tournament","location":"Main Field"}`
+	req := newEventRequest(t, http.MethodPost, "", body)
+	rr := httptest.NewRecorder()
+
+	handler.CreateEvent(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	var created models.Event
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("Expected created event to have an ID")
+	}
+	if location := rr.Header().Get("Location"); location != "/events/"+created.ID {
+		t.Errorf("Expected Location header '/events/%s', got '%s'", created.ID, location)
+	}
+
+	if created.Organization != testOrganization {
+		t.Errorf("Expected organization '%s', got '%s'", testOrganization, created.Organization)
+	}
+
+	tenant := repository.Tenant{Organizations: []string{testOrganization}}
+	stored, err := mockRepo.GetEventByID(req.Context(), tenant, created.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("Expected event to be stored, got %v (err %v)", stored, err)
+	}
+	if stored.Title != "Summer Cup" {
+		t.Errorf("Expected stored title 'Summer Cup', got '%s'", stored.Title)
+	}
+}
+
+// Test that invalid payloads are rejected with field-level errors
+func TestCreateEventValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{
+			name:       "missing everything",
+			body:       `{}`,
+			wantFields: []string{"title", "date", "location"},
+		},
+		{
+			name:       "title too long",
+			body:       `{"date":"2030-06-01T18:00:00Z","title":"` + strings.Repeat("a", models.MaxTitleLength+1) + `","location":"Main Field"}`,
+			wantFields: []string{"title"},
+		},
+		{
+			name:       "malformed date",
+			body:       `{"date":"next friday","title":"Summer Cup","location":"Main Field"}`,
+			wantFields: []string{"date"},
+		},
+		{
+			name:       "blank location",
+			body:       `{"date":"2030-06-01T18:00:00Z","title":"Summer Cup","location":"   "}`,
+			wantFields: []string{"location"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEventsHandler(repository.NewMockEventsRepository())
+			rr := httptest.NewRecorder()
+
+			handler.CreateEvent(rr, newEventRequest(t, http.MethodPost, "", tt.body))
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+
+			var resp validationErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if len(resp.Fields) != len(tt.wantFields) {
+				t.Errorf("Expected %d field errors, got %v", len(tt.wantFields), resp.Fields)
+			}
+			for _, field := range tt.wantFields {
+				if _, ok := resp.Fields[field]; !ok {
+					t.Errorf("Expected field error for '%s', got %v", field, resp.Fields)
+				}
+			}
+		})
+	}
+}
+
+// Test that malformed JSON is rejected
+func TestCreateEventMalformedJSON(t *testing.T) {
+	handler := NewEventsHandler(repository.NewMockEventsRepository())
+	rr := httptest.NewRecorder()
+
+	handler.CreateEvent(rr, newEventRequest(t, http.MethodPost, "", `{"title":`))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// Test updating an existing and a non-existent event
+func TestUpdateEvent(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+	handler := NewEventsHandler(mockRepo)
+	body := `{"date":"2030-06-01T18:00:00Z","title":"Renamed Match","location":"New Field"}`
+
+	rr := httptest.NewRecorder()
+	handler.UpdateEvent(rr, newEventRequest(t, http.MethodPut, mockRepo.FixedEventID, body))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var updated models.Event
+	if err := json.NewDecoder(rr.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if updated.ID != mockRepo.FixedEventID || updated.Title != "Renamed Match" {
+		t.Errorf("Unexpected updated event: %+v", updated)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.UpdateEvent(rr, newEventRequest(t, http.MethodPut, "non-existent-id", body))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+// Test deleting an existing and a non-existent event
+func TestDeleteEvent(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+	handler := NewEventsHandler(mockRepo)
+
+	rr := httptest.NewRecorder()
+	req := newEventRequest(t, http.MethodDelete, mockRepo.FixedEventID, "")
+	handler.DeleteEvent(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	tenant := repository.Tenant{Organizations: []string{testOrganization}}
+	if event, _ := mockRepo.GetEventByID(req.Context(), tenant, mockRepo.FixedEventID); event != nil {
+		t.Error("Expected event to be deleted")
+	}
+
+	rr = httptest.NewRecorder()
+	handler.DeleteEvent(rr, newEventRequest(t, http.MethodDelete, mockRepo.FixedEventID, ""))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+// Test that events are scoped to the caller's organizations
+func TestEventsTenantIsolation(t *testing.T) {
+	member := func(orgs ...string) *oauth.AuthClaims {
+		claims := &oauth.AuthClaims{}
+		for _, org := range orgs {
+			claims.Organizations = append(claims.Organizations, oauth.Organization{Alias: org, ID: "id-" + org})
+		}
+		return claims
+	}
+	admin := &oauth.AuthClaims{Roles: []string{"system-admin"}}
+	body := `{"date":"2030-06-01T18:00:00Z","title":"Summer Cup","location":"Main Field"}`
+	mockRepo := repository.NewMockEventsRepository()
+
+	tests := []struct {
+		name       string
+		claims     *oauth.AuthClaims
+		method     string
+		id         string
+		query      string
+		wantStatus int
+	}{
+		{name: "Read own event", claims: member(testOrganization), method: http.MethodGet, id: mockRepo.FixedEventID, wantStatus: http.StatusOK},
+		{name: "Read event of other organization", claims: member(testOrganization), method: http.MethodGet, id: mockRepo.OtherEventID, wantStatus: http.StatusNotFound},
+		{name: "Update event of other organization", claims: member(testOrganization), method: http.MethodPut, id: mockRepo.OtherEventID, wantStatus: http.StatusNotFound},
+		{name: "Delete event of other organization", claims: member(testOrganization), method: http.MethodDelete, id: mockRepo.OtherEventID, wantStatus: http.StatusNotFound},
+		{name: "Read with own organization id", claims: member(testOrganization), method: http.MethodGet, id: mockRepo.FixedEventID, query: "id-" + testOrganization, wantStatus: http.StatusOK},
+		{name: "Name foreign organization", claims: member(testOrganization), method: http.MethodGet, id: mockRepo.OtherEventID, query: mockRepo.OtherOrganization, wantStatus: http.StatusNotFound},
+		{name: "Alias with a comma", claims: member(testOrganization + "," + mockRepo.OtherOrganization), method: http.MethodGet, id: mockRepo.FixedEventID, wantStatus: http.StatusNotFound},
+		{name: "Admin names alias with a comma", claims: admin, method: http.MethodGet, id: mockRepo.FixedEventID, query: url.QueryEscape(testOrganization + "," + mockRepo.OtherOrganization), wantStatus: http.StatusNotFound},
+		{name: "No organization membership", claims: member(), method: http.MethodGet, id: mockRepo.FixedEventID, wantStatus: http.StatusForbidden},
+		{name: "No claims", method: http.MethodGet, id: mockRepo.FixedEventID, wantStatus: http.StatusForbidden},
+		{name: "Admin names organization", claims: admin, method: http.MethodGet, id: mockRepo.OtherEventID, query: mockRepo.OtherOrganization, wantStatus: http.StatusOK},
+		{name: "Admin names other organization of the event", claims: admin, method: http.MethodGet, id: mockRepo.OtherEventID, query: testOrganization, wantStatus: http.StatusNotFound},
+		{name: "Admin updates event of named organization", claims: admin, method: http.MethodPut, id: mockRepo.OtherEventID, query: mockRepo.OtherOrganization, wantStatus: http.StatusOK},
+		{name: "Admin without organization", claims: admin, method: http.MethodGet, id: mockRepo.OtherEventID, wantStatus: http.StatusForbidden},
+		{name: "Create as member of several organizations", claims: member(testOrganization, mockRepo.OtherOrganization), method: http.MethodPost, wantStatus: http.StatusBadRequest},
+		{name: "Create in named organization", claims: member(testOrganization, mockRepo.OtherOrganization), method: http.MethodPost, query: mockRepo.OtherOrganization, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMockEventsRepository()
+			handler := NewEventsHandler(repo)
+
+			req := newEventRequest(t, tt.method, tt.id, body)
+			req = oauth.SetAuthClaims(req, tt.claims)
+			if tt.query != "" {
+				req.URL.RawQuery = "organization=" + tt.query
+			}
+			rr := httptest.NewRecorder()
+
+			switch tt.method {
+			case http.MethodGet:
+				handler.GetEventByID(rr, req)
+			case http.MethodPost:
+				handler.CreateEvent(rr, req)
+			case http.MethodPut:
+				handler.UpdateEvent(rr, req)
+			case http.MethodDelete:
+				handler.DeleteEvent(rr, req)
+			}
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+// Test that the admin role is taken from WithAdminRole
+func TestEventsHandler_WithAdminRole(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+
+	tests := []struct {
+		name       string
+		opts       []EventsHandlerOption
+		role       string
+		wantStatus int
+	}{
+		{name: "Default admin role", role: defaultAdminRole, wantStatus: http.StatusOK},
+		{name: "Configured admin role", opts: []EventsHandlerOption{WithAdminRole("tenant-auditor")}, role: "tenant-auditor", wantStatus: http.StatusOK},
+		{name: "Default replaced", opts: []EventsHandlerOption{WithAdminRole("tenant-auditor")}, role: defaultAdminRole, wantStatus: http.StatusNotFound},
+		{name: "Empty role keeps default", opts: []EventsHandlerOption{WithAdminRole("")}, role: defaultAdminRole, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewEventsHandler(mockRepo, tt.opts...)
+
+			req := newEventRequest(t, http.MethodGet, mockRepo.OtherEventID, "")
+			req = oauth.SetAuthClaims(req, &oauth.AuthClaims{
+				Roles:         []string{tt.role},
+				Organizations: []oauth.Organization{{Alias: testOrganization, ID: "id-" + testOrganization}},
+			})
+			req.URL.RawQuery = "organization=" + mockRepo.OtherOrganization
+			rr := httptest.NewRecorder()
+
+			handler.GetEventByID(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+// Test that listing events only returns those of the caller's organizations
+func TestGetEventsTenantFilter(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+	handler := NewEventsHandler(mockRepo)
+
+	tests := []struct {
+		name      string
+		orgs      []string
+		query     string
+		wantCount int
+	}{
+		{name: "Single organization", orgs: []string{testOrganization}, wantCount: 3},
+		{name: "Other organization", orgs: []string{mockRepo.OtherOrganization}, wantCount: 1},
+		{name: "Several organizations", orgs: []string{testOrganization, mockRepo.OtherOrganization}, wantCount: 4},
+		{name: "Several organizations, one named", orgs: []string{testOrganization, mockRepo.OtherOrganization}, query: mockRepo.OtherOrganization, wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &oauth.AuthClaims{}
+			for _, org := range tt.orgs {
+				claims.Organizations = append(claims.Organizations, oauth.Organization{Alias: org})
+			}
+			req := oauth.SetAuthClaims(httptest.NewRequest(http.MethodGet, "/events?organization="+tt.query, nil), claims)
+			rr := httptest.NewRecorder()
+
+			handler.GetEvents(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			var events models.Events
+			if err := json.NewDecoder(rr.Body).Decode(&events); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if len(events) != tt.wantCount {
+				t.Errorf("Expected %d events, got %d", tt.wantCount, len(events))
+			}
+			for _, event := range events {
+				if !slices.Contains(tt.orgs, event.Organization) {
+					t.Errorf("Event %s of organization %s returned to member of %v", event.ID, event.Organization, tt.orgs)
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"slices"
 
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/middleware"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/repository"
 )
 
 // SetupRoutes configures all the HTTP routes for the application
@@ -15,16 +17,41 @@ func SetupRoutes(eventsHandler *EventsHandler, authConfig config.AuthConfig) {
 	SetupRoutesWithContext(context.Background(), eventsHandler, authConfig)
 }
 
+// RouteOptions holds optional dependencies for SetupRoutesWithOptions
+type RouteOptions struct {
+	// HTTPClient is used for calls to Keycloak; defaults to http.Client
+	HTTPClient oauth.HTTPClient
+
+	// TokenRevocations enables the jti denylist check for all protected routes
+	// and the POST /admin/revoked-tokens endpoint
+	TokenRevocations repository.TokenRevocationRepository
+}
+
 // SetupRoutesWithContext configures all the HTTP routes with a context for validator lifecycle
 // An optional HTTPClient can be provided for testing purposes
 func SetupRoutesWithContext(ctx context.Context, eventsHandler *EventsHandler, authConfig config.AuthConfig, client ...oauth.HTTPClient) {
+	var opts RouteOptions
+	if len(client) > 0 {
+		opts.HTTPClient = client[0]
+	}
+	SetupRoutesWithOptions(ctx, eventsHandler, authConfig, opts)
+}
+
+// SetupRoutesWithOptions configures all the HTTP routes with a context for validator lifecycle
+// and optional dependencies
+func SetupRoutesWithOptions(ctx context.Context, eventsHandler *EventsHandler, authConfig config.AuthConfig, opts RouteOptions) {
 	// Create CORS middleware
 	cors := middleware.NewCORSMiddleware(middleware.DefaultCORSConfig())
 
-	// Use provided client or default to http.Client
-	var httpClient oauth.HTTPClient = &http.Client{}
-	if len(client) > 0 && client[0] != nil {
-		httpClient = client[0]
+	// Use provided client or default to a client with timeouts, retries and a circuit breaker
+	var httpClient oauth.HTTPClient = oauth.NewIdPClient(authConfig)
+	if opts.HTTPClient != nil {
+		httpClient = opts.HTTPClient
+	}
+
+	// Fail fast on client authentication misconfiguration, e.g. an unreadable key file
+	if _, err := oauth.NewClientAuthenticator(authConfig); err != nil {
+		log.Fatalf("Invalid client authentication configuration: %v", err)
 	}
 
 	// Create validator based on config
@@ -38,38 +65,146 @@ func SetupRoutesWithContext(ctx context.Context, eventsHandler *EventsHandler, a
 		log.Fatalf("Failed to create token validator: %v", err)
 	}
 
-	// Create AuthN middleware using the validator
-	authN := middleware.NewAuthMiddlewareWithValidator(validator)
+	// Reject tokens of sessions ended via back-channel logout
+	if authConfig.BackchannelLogoutEnabled {
+		revocations := oauth.NewSessionRevocations(authConfig.BackchannelLogoutRetention)
+		validator = oauth.NewRevocationCheckingValidator(validator, revocations)
+
+		// Logout tokens are always verified locally, whatever the validation method,
+		// and accepted from every trusted issuer
+		var logoutValidator LogoutTokenValidator
+		if len(authConfig.TrustedIssuers) > 0 {
+			logoutValidator, err = oauth.NewMultiIssuerLogoutValidatorFromConfig(ctx, authConfig, httpClient)
+		} else {
+			var jwksValidator *oauth.JWKSValidator
+			jwksValidator, err = oauth.NewJWKSValidatorFromConfig(ctx, authConfig, httpClient)
+			logoutValidator = oauth.NewLogoutTokenValidator(jwksValidator, authConfig.BackchannelLogoutAudiences...)
+		}
+		if err != nil {
+			log.Fatalf("Failed to create logout token validator: %v", err)
+		}
+		logoutHandler := NewBackchannelLogoutHandler(logoutValidator, revocations)
+
+		// Called by Keycloak directly, so neither CORS nor bearer auth apply
+		http.Handle("/backchannel-logout", byMethod(map[string]http.HandlerFunc{
+			http.MethodPost: logoutHandler.Logout,
+		}))
+	}
+
+	// Reject individually revoked tokens
+	if opts.TokenRevocations != nil {
+		validator = oauth.NewDenylistValidator(validator, opts.TokenRevocations)
+	}
+
+	// Create AuthN middleware using the validator, accepting DPoP-bound tokens if configured
+	dpopMode, err := middleware.ParseDPoPMode(authConfig.DPoPMode)
+	if err != nil {
+		log.Fatalf("Invalid DPoP configuration: %v", err)
+	}
+	authnOpts := middleware.AuthnOptions{DPoP: dpopMode, PublicURL: authConfig.PublicURL}
+	if dpopMode != middleware.DPoPDisabled || len(authConfig.DPoPRequiredRoutes) > 0 {
+		// One verifier for all routes, so proof replays are detected across them
+		authnOpts.DPoPVerifier = oauth.NewDPoPVerifier(authConfig.DPoPProofMaxAge, authConfig.DPoPNonceRequired)
+	}
+
+	// Sensitive routes confirm the token is still active on reads as well, e.g. via
+	// introspection in hybrid mode; routes listed in DPoPRequiredRoutes reject Bearer
+	// tokens, all others follow DPoPMode
+	authenticate := func(pattern string, sensitive bool) func(http.Handler) http.Handler {
+		routeOpts := authnOpts
+		routeOpts.Sensitive = sensitive || slices.Contains(authConfig.SensitiveRoutes, pattern)
+		if slices.Contains(authConfig.DPoPRequiredRoutes, pattern) {
+			routeOpts.DPoP = middleware.DPoPRequired
+		}
+		return middleware.NewAuthMiddlewareWithOptions(validator, routeOpts)
+	}
 
-	// Create AuthZ middleware (checks required scopes)
+	// Create AuthZ middleware: either Keycloak Authorization Services decide per
+	// resource scope, or the token must carry the required scope
 	authZ := middleware.NewAuthzMiddleware(middleware.AuthzConfig{
 		RequiredScopes: []string{authConfig.RequiredScope},
 		RequireAll:     true,
 	})
+	if authConfig.PolicyEnforcerEnabled {
+		// Each route checks a scope of PolicyResource unless PolicyPermissions maps it elsewhere
+		read := middleware.ResourceScope{Resource: authConfig.PolicyResource, Scope: "read"}
+		write := middleware.ResourceScope{Resource: authConfig.PolicyResource, Scope: "write"}
+		permissions := map[string]middleware.ResourceScope{
+			"GET /events":         read,
+			"POST /events":        write,
+			"GET /events/":        read,
+			"GET /events/{id}":    read,
+			"PUT /events/{id}":    write,
+			"DELETE /events/{id}": {Resource: authConfig.PolicyResource, Scope: "delete"},
+		}
+		for _, entry := range authConfig.PolicyPermissions {
+			route, permission, err := middleware.ParsePolicyPermission(entry)
+			if err != nil {
+				log.Fatalf("Invalid policy enforcer configuration: %v", err)
+			}
+			permissions[route] = permission
+		}
+		authZ = middleware.NewPolicyEnforcerMiddleware(
+			oauth.NewPermissionEvaluator(authConfig, httpClient),
+			middleware.PolicyEnforcerConfig{Permissions: permissions},
+		)
+	}
 
-	// Helper function to chain CORS -> AuthN -> AuthZ middlewares
-	protected := func(h http.Handler) http.Handler {
-		return cors(authN(authZ(h)))
+	// Helper function to chain CORS -> AuthN -> AuthZ middlewares for a route pattern
+	protected := func(pattern string, h http.Handler) http.Handler {
+		return cors(authenticate(pattern, false)(authZ(h)))
 	}
 
 	// Register the handler for the /events/{id} endpoint using Go 1.22 path variables
 	// This must be registered first as it's more specific than "/events/"
-	http.Handle("/events/{id}", protected(http.HandlerFunc(eventsHandler.GetEventByID)))
+	http.Handle("/events/{id}", protected("/events/{id}", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:    eventsHandler.GetEventByID,
+		http.MethodPut:    eventsHandler.UpdateEvent,
+		http.MethodDelete: eventsHandler.DeleteEvent,
+	})))
 
 	// Register the handler for the /events endpoint
-	http.Handle("/events", protected(http.HandlerFunc(eventsHandler.GetEvents)))
+	http.Handle("/events", protected("/events", byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  eventsHandler.GetEvents,
+		http.MethodPost: eventsHandler.CreateEvent,
+	})))
 
 	// Handle the specific case of "/events/" to redirect to "/events"
-	http.Handle("/events/", protected(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/events/", protected("/events/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/events/" {
 			http.Redirect(w, r, "/events", http.StatusMovedPermanently)
 			return
 		}
 	})))
 
+	// Register the admin endpoint for the token denylist (system admins only), always sensitive
+	if opts.TokenRevocations != nil {
+		adminZ := middleware.NewAuthzMiddleware(middleware.AuthzConfig{
+			RequiredRoles: []string{authConfig.AdminRole},
+			RequireAll:    true,
+		})
+		revocationsHandler := NewTokenRevocationsHandler(opts.TokenRevocations)
+		http.Handle("/admin/revoked-tokens", cors(authenticate("/admin/revoked-tokens", true)(adminZ(byMethod(map[string]http.HandlerFunc{
+			http.MethodPost: revocationsHandler.RevokeToken,
+		})))))
+	}
+
 	// Add a simple health check endpoint (CORS only, no auth required)
 	http.Handle("/health", cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})))
 }
+
+// byMethod dispatches a request to the handler registered for its HTTP method
+// Requests with any other method are rejected with 405 Method Not Allowed
+func byMethod(handlers map[string]http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[r.Method]
+		if !ok {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	})
+}
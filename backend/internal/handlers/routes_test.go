@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/repository"
 )
 
@@ -71,7 +74,7 @@ func TestRoutesSetup(t *testing.T) {
 		{
 			name:           "Method not allowed for events",
 			path:           "/events",
-			method:         http.MethodPost,
+			method:         http.MethodPatch,
 			expectedStatus: http.StatusMethodNotAllowed,
 			validateBody:   false,
 		},
@@ -82,6 +85,20 @@ func TestRoutesSetup(t *testing.T) {
 			expectedStatus: http.StatusMethodNotAllowed,
 			validateBody:   false,
 		},
+		{
+			name:           "Create event with empty body",
+			path:           "/events",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			validateBody:   false,
+		},
+		{
+			name:           "Delete non-existent event",
+			path:           "/events/non-existent-id",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusNotFound,
+			validateBody:   false,
+		},
 		{
 			name:           "Health check",
 			path:           "/health",
@@ -164,3 +181,146 @@ func TestRoutesSetup(t *testing.T) {
 		})
 	}
 }
+
+// TestRoutes_FakeRealm runs the full router against an in-process fake Keycloak realm
+func TestRoutes_FakeRealm(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+
+	validToken := realm.MintToken(oauthtest.TokenOptions{Scopes: []string{"events-api-access"}, Organizations: []string{testOrganization}})
+	revokedToken := realm.MintToken(oauthtest.TokenOptions{Scopes: []string{"events-api-access"}, Organizations: []string{testOrganization}})
+	realm.Revoke(revokedToken)
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		expectedStatus int
+	}{
+		{name: "Valid token", method: "introspection", token: validToken, expectedStatus: http.StatusOK},
+		{name: "Valid token", method: "jwks", token: validToken, expectedStatus: http.StatusOK},
+		{name: "Revoked token", method: "introspection", token: revokedToken, expectedStatus: http.StatusUnauthorized},
+		{name: "Revoked token", method: "hybrid", token: revokedToken, expectedStatus: http.StatusUnauthorized},
+		// Local validation alone cannot see revocations
+		{name: "Revoked token", method: "jwks", token: revokedToken, expectedStatus: http.StatusOK},
+		{
+			name:           "Missing required scope",
+			method:         "jwks",
+			token:          realm.MintToken(oauthtest.TokenOptions{Scopes: []string{"profile"}}),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Expired token",
+			method:         "jwks",
+			token:          realm.MintToken(oauthtest.TokenOptions{Scopes: []string{"events-api-access"}, Organizations: []string{testOrganization}, ExpiresIn: -time.Minute}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{name: "No token", method: "introspection", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" with "+tt.method, func(t *testing.T) {
+			authConfig := realm.AuthConfig()
+			authConfig.ValidationMethod = tt.method
+
+			mux := http.NewServeMux()
+			http.DefaultServeMux = mux
+			SetupRoutesWithContext(context.Background(), NewEventsHandler(repository.NewMockEventsRepository()), authConfig)
+
+			req := httptest.NewRequest(http.MethodGet, "/events", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+// TestRoutes_SensitiveRoute checks that reads on sensitive routes are validated strictly,
+// so a revoked token is rejected there while still accepted within the hybrid recheck
+// interval elsewhere
+func TestRoutes_SensitiveRoute(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	mockRepo := repository.NewMockEventsRepository()
+
+	authConfig := realm.AuthConfig()
+	authConfig.ValidationMethod = "hybrid"
+	authConfig.HybridRecheckInterval = time.Hour
+	authConfig.SensitiveRoutes = []string{"/events/{id}"}
+
+	mux := http.NewServeMux()
+	http.DefaultServeMux = mux
+	SetupRoutesWithContext(context.Background(), NewEventsHandler(mockRepo), authConfig)
+
+	token := realm.MintToken(oauthtest.TokenOptions{Scopes: []string{"events-api-access"}, Organizations: []string{mockRepo.FixedOrganization}})
+	get := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The first use of a token is introspected, afterwards it is trusted until the recheck
+	if status := get("/events"); status != http.StatusOK {
+		t.Fatalf("status before revocation = %d, want %d", status, http.StatusOK)
+	}
+	realm.Revoke(token)
+
+	if status := get("/events"); status != http.StatusOK {
+		t.Errorf("status on regular route = %d, want %d", status, http.StatusOK)
+	}
+	if status := get("/events/" + mockRepo.FixedEventID); status != http.StatusUnauthorized {
+		t.Errorf("status on sensitive route = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+// TestRoutes_DPoPRequiredRoute checks that routes listed in DPoPRequiredRoutes reject Bearer
+// tokens while the other routes follow DPoPMode
+func TestRoutes_DPoPRequiredRoute(t *testing.T) {
+	mockRepo := repository.NewMockEventsRepository()
+	authConfig := config.AuthConfig{
+		KeycloakURL:        "http://mock-keycloak:8080",
+		ClientID:           "test-client",
+		ClientSecret:       "test-secret",
+		RequiredScope:      "test-scope",
+		DPoPMode:           "allowed",
+		DPoPRequiredRoutes: []string{"/events/{id}"},
+	}
+
+	mux := http.NewServeMux()
+	http.DefaultServeMux = mux
+	SetupRoutesWithContext(context.Background(), NewEventsHandler(mockRepo), authConfig, createMockHTTPClient())
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		wantChallenge  string
+	}{
+		{name: "Bearer allowed", path: "/events", expectedStatus: http.StatusOK},
+		{name: "DPoP required", path: "/events/" + mockRepo.FixedEventID, expectedStatus: http.StatusUnauthorized, wantChallenge: "DPoP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer valid-token")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if challenge := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, tt.wantChallenge) {
+				t.Errorf("WWW-Authenticate = %q, want prefix %q", challenge, tt.wantChallenge)
+			}
+		})
+	}
+}
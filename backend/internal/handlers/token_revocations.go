@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/repository"
+)
+
+// maxRevocationPayloadBytes limits the size of revocation request bodies, which hold
+// a jti of at most models.MaxJTILength characters, a timestamp and a short reason
+const maxRevocationPayloadBytes = 4 << 10
+
+// revokedTokenPayload is the request body accepted by RevokeToken
+// ExpiresAt is kept as a string so that a malformed value can be reported as a field error
+type revokedTokenPayload struct {
+	JTI       string `json:"jti"`
+	ExpiresAt string `json:"expires_at"`
+	Reason    string `json:"reason"`
+}
+
+// TokenRevocationsHandler handles HTTP requests for the access token denylist
+type TokenRevocationsHandler struct {
+	repo repository.TokenRevocationRepository
+}
+
+// NewTokenRevocationsHandler creates a new TokenRevocationsHandler
+func NewTokenRevocationsHandler(repo repository.TokenRevocationRepository) *TokenRevocationsHandler {
+	return &TokenRevocationsHandler{
+		repo: repo,
+	}
+}
+
+// RevokeToken validates the request body and adds the token to the denylist
+// The entry takes effect immediately for both introspection and JWKS validation
+func (h *TokenRevocationsHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var payload revokedTokenPayload
+	r.Body = http.MaxBytesReader(w, r.Body, maxRevocationPayloadBytes)
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	revoked := &models.RevokedToken{
+		JTI:       strings.TrimSpace(payload.JTI),
+		Reason:    payload.Reason,
+		RevokedAt: time.Now().UTC(),
+	}
+	if claims := oauth.GetAuthClaims(r); claims != nil {
+		revoked.RevokedBy = claims.Subject
+	}
+
+	// Parse the expiry separately so a malformed value becomes a field error
+	var expiresAtErr string
+	if strings.TrimSpace(payload.ExpiresAt) != "" {
+		expiresAt, err := time.Parse(time.RFC3339, payload.ExpiresAt)
+		if err != nil {
+			expiresAtErr = "expires_at must be an RFC 3339 timestamp"
+		}
+		revoked.ExpiresAt = expiresAt
+	}
+
+	errs := revoked.Validate()
+	if expiresAtErr != "" {
+		if errs == nil {
+			errs = models.ValidationErrors{}
+		}
+		errs["expires_at"] = expiresAtErr
+	}
+	if errs != nil {
+		writeJSON(w, http.StatusBadRequest, validationErrorResponse{
+			Error:  "validation failed",
+			Fields: errs,
+		})
+		return
+	}
+
+	if err := h.repo.RevokeToken(r.Context(), revoked); err != nil {
+		log.Printf("Error revoking token: %v", err)
+		http.Error(w, "Error revoking token", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Token %q revoked by %q: %s", revoked.JTI, revoked.RevokedBy, revoked.Reason)
+	writeJSON(w, http.StatusCreated, revoked)
+}
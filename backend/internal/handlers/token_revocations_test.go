@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/repository"
+)
+
+func TestRevokeToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedFields []string
+	}{
+		{
+			name:           "Valid revocation",
+			body:           `{"jti":"leaked-jti","expires_at":"` + expiresAt + `","reason":"posted in public chat"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Missing fields",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"jti", "expires_at", "reason"},
+		},
+		{
+			name:           "Malformed expiry",
+			body:           `{"jti":"leaked-jti","expires_at":"tomorrow","reason":"leak"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"expires_at"},
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `{"jti":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Oversized body",
+			body:           `{"jti":"leaked-jti","expires_at":"` + expiresAt + `","reason":"` + strings.Repeat("a", maxRevocationPayloadBytes) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMockTokenRevocationRepository()
+			handler := NewTokenRevocationsHandler(repo)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/revoked-tokens", strings.NewReader(tt.body))
+			req = oauth.SetAuthClaims(req, &oauth.AuthClaims{Subject: "admin-1"})
+			rr := httptest.NewRecorder()
+
+			handler.RevokeToken(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedStatus == http.StatusCreated {
+				var revoked models.RevokedToken
+				if err := json.NewDecoder(rr.Body).Decode(&revoked); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if revoked.RevokedBy != "admin-1" {
+					t.Errorf("RevokedBy = %v, want admin-1", revoked.RevokedBy)
+				}
+				if isRevoked, _ := repo.IsTokenRevoked(context.Background(), "leaked-jti"); !isRevoked {
+					t.Error("expected leaked-jti to be revoked")
+				}
+			}
+
+			if len(tt.expectedFields) > 0 {
+				var resp validationErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				for _, field := range tt.expectedFields {
+					if _, ok := resp.Fields[field]; !ok {
+						t.Errorf("Expected field error for %q, got %v", field, resp.Fields)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRoutesSetup_TokenDenylist(t *testing.T) {
+	revocations := repository.NewMockTokenRevocationRepository()
+
+	mux := http.NewServeMux()
+	http.DefaultServeMux = mux
+	SetupRoutesWithOptions(context.Background(), NewEventsHandler(repository.NewMockEventsRepository()), config.AuthConfig{
+		KeycloakURL:   "http://mock-keycloak:8080",
+		ClientID:      "test-client",
+		ClientSecret:  "test-secret",
+		RequiredScope: "test-scope",
+		AdminRole:     "system-admin",
+	}, RouteOptions{
+		HTTPClient:       createMockHTTPClient(),
+		TokenRevocations: revocations,
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	send := func(method, path, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer valid-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := send(http.MethodGet, "/events", ""); status != http.StatusOK {
+		t.Fatalf("GET /events before revocation = %d, want %d", status, http.StatusOK)
+	}
+
+	// The mock token has no system-admin role
+	if status := send(http.MethodPost, "/admin/revoked-tokens", `{}`); status != http.StatusForbidden {
+		t.Errorf("POST /admin/revoked-tokens without admin role = %d, want %d", status, http.StatusForbidden)
+	}
+
+	// The mock introspection response carries jti "test-jti"
+	revocations.RevokeToken(context.Background(), &models.RevokedToken{
+		JTI:       "test-jti",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Reason:    "leaked",
+	})
+	if status := send(http.MethodGet, "/events", ""); status != http.StatusUnauthorized {
+		t.Errorf("GET /events after revocation = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
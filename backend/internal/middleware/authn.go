@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -11,30 +13,135 @@ import (
 
 // NewAuthMiddleware creates a new auth middleware with the given configuration
 func NewAuthMiddleware(authConfig config.AuthConfig) func(http.Handler) http.Handler {
-	return NewIntrospectionAuthMiddlewareWithClient(authConfig, &http.Client{})
+	return NewIntrospectionAuthMiddlewareWithClient(authConfig, oauth.NewIdPClient(authConfig))
+}
+
+// DPoPMode selects which authorization schemes a route accepts
+type DPoPMode int
+
+const (
+	// DPoPDisabled accepts only Bearer tokens
+	DPoPDisabled DPoPMode = iota
+	// DPoPAllowed accepts Bearer tokens and DPoP-bound tokens with a DPoP proof
+	DPoPAllowed
+	// DPoPRequired accepts only DPoP-bound tokens with a DPoP proof
+	DPoPRequired
+)
+
+// ParseDPoPMode parses "disabled" (or ""), "allowed" or "required"
+func ParseDPoPMode(mode string) (DPoPMode, error) {
+	switch mode {
+	case "", "disabled":
+		return DPoPDisabled, nil
+	case "allowed":
+		return DPoPAllowed, nil
+	case "required":
+		return DPoPRequired, nil
+	default:
+		return DPoPDisabled, fmt.Errorf("unsupported DPoP mode: %s", mode)
+	}
+}
+
+// DPoPProofVerifier validates DPoP proofs (see oauth.DPoPVerifier)
+type DPoPProofVerifier interface {
+	VerifyProof(proof, method, requestURL, accessToken string) (*oauth.DPoPProof, error)
+	Nonce() string
+}
+
+// AuthnOptions holds per-route configuration for the auth middleware
+type AuthnOptions struct {
+	// Sensitive forces a strict, revocation-aware validation on every request
+	// if the validator supports it (see oauth.StrictTokenValidator)
+	// Write methods (POST, PUT, PATCH, DELETE) are always validated strictly
+	Sensitive bool
+
+	// DPoP selects between Bearer only, Bearer or DPoP, and DPoP only
+	// DPoP-bound tokens (cnf.jkt) are never accepted with the Bearer scheme
+	DPoP DPoPMode
+	// DPoPVerifier validates proofs; required unless DPoP is DPoPDisabled
+	DPoPVerifier DPoPProofVerifier
+	// PublicURL is the externally visible base URL (e.g. https://api.example.com) that
+	// clients use in the proof's htu; defaults to the scheme and Host of the request
+	PublicURL string
 }
 
 // NewAuthMiddlewareWithValidator creates a new auth middleware using a TokenValidator
 func NewAuthMiddlewareWithValidator(validator oauth.TokenValidator) func(http.Handler) http.Handler {
+	return NewAuthMiddlewareWithOptions(validator, AuthnOptions{})
+}
+
+// NewAuthMiddlewareWithOptions creates a new auth middleware using a TokenValidator and per-route options
+func NewAuthMiddlewareWithOptions(validator oauth.TokenValidator, opts AuthnOptions) func(http.Handler) http.Handler {
+	strictValidator, hasStrict := validator.(oauth.StrictTokenValidator)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract Bearer token from Authorization header
-			token, ok := extractBearerToken(r)
-			if !ok {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			// Extract the token from the Authorization header
+			scheme, token, ok := extractAuthorization(r)
+			if !ok || (scheme == schemeBearer && opts.DPoP == DPoPRequired) || (scheme == schemeDPoP && opts.DPoP == DPoPDisabled) {
+				unauthorized(w, opts, "")
 				return
 			}
 
+			// Verify the DPoP proof before spending a validation on the token
+			var proof *oauth.DPoPProof
+			if scheme == schemeDPoP {
+				var err error
+				proof, err = verifyDPoPProof(r, token, opts)
+				if err != nil {
+					log.Printf("DPoP proof error: %v", err)
+					if errors.Is(err, oauth.ErrDPoPNonceRequired) {
+						w.Header().Set("DPoP-Nonce", opts.DPoPVerifier.Nonce())
+						unauthorized(w, opts, "use_dpop_nonce")
+						return
+					}
+					unauthorized(w, opts, "invalid_dpop_proof")
+					return
+				}
+			}
+
 			// Validate token using the validator and get claims
-			claims, err := validator.ValidateToken(token)
+			// Sensitive routes and write methods get the strict check when available
+			var claims *oauth.AuthClaims
+			var err error
+			if hasStrict && (opts.Sensitive || isWriteMethod(r.Method)) {
+				claims, err = strictValidator.ValidateTokenStrict(r.Context(), token)
+			} else {
+				claims, err = validator.ValidateToken(r.Context(), token)
+			}
 			if err != nil {
 				log.Printf("Token validation error: %v", err)
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				// An unreachable identity provider says nothing about the token
+				if errors.Is(err, oauth.ErrIdPUnavailable) {
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				unauthorized(w, opts, "invalid_token")
+				return
+			}
+
+			// The token must be bound to the proof key, and bound tokens require a proof
+			if proof != nil && claims.Confirmation.JKT != proof.JKT {
+				log.Printf("DPoP key binding mismatch for subject %q", claims.Subject)
+				unauthorized(w, opts, "invalid_token")
+				return
+			}
+			if proof == nil && claims.Confirmation.JKT != "" {
+				log.Printf("DPoP-bound token presented as Bearer for subject %q", claims.Subject)
+				unauthorized(w, opts, "invalid_token")
+				return
+			}
+
+			// Certificate-bound tokens require the matching client certificate
+			if err := oauth.VerifyCertificateBinding(claims, r.TLS); err != nil {
+				log.Printf("Certificate binding error for subject %q: %v", claims.Subject, err)
+				unauthorized(w, opts, "invalid_token")
 				return
 			}
 
-			// Store claims in request context
+			// Store claims and the validated token in request context
 			r = oauth.SetAuthClaims(r, claims)
+			r = oauth.SetAccessToken(r, token)
 
 			// Call the next handler with the enriched request
 			next.ServeHTTP(w, r)
@@ -42,6 +149,43 @@ func NewAuthMiddlewareWithValidator(validator oauth.TokenValidator) func(http.Ha
 	}
 }
 
+// verifyDPoPProof checks the request carries exactly one valid DPoP proof for the token
+func verifyDPoPProof(r *http.Request, token string, opts AuthnOptions) (*oauth.DPoPProof, error) {
+	proofs := r.Header.Values("DPoP")
+	if len(proofs) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one DPoP header, got %d", oauth.ErrInvalidDPoPProof, len(proofs))
+	}
+	return opts.DPoPVerifier.VerifyProof(proofs[0], r.Method, requestURL(r, opts.PublicURL), token)
+}
+
+// requestURL reconstructs the URL the client sent the request to, without query
+func requestURL(r *http.Request, publicURL string) string {
+	if publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/") + r.URL.EscapedPath()
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+// unauthorized writes a 401 response
+// Routes accepting DPoP announce the scheme and, if given, the error in WWW-Authenticate
+func unauthorized(w http.ResponseWriter, opts AuthnOptions, dpopError string) {
+	if opts.DPoP != DPoPDisabled {
+		challenge := `DPoP algs="ES256 ES384 ES512 RS256 RS384 RS512 PS256 PS384 PS512 EdDSA"`
+		if dpopError != "" {
+			challenge += `, error="` + dpopError + `"`
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+		if opts.DPoP == DPoPAllowed {
+			w.Header().Add("WWW-Authenticate", "Bearer")
+		}
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
 // NewIntrospectionAuthMiddlewareWithClient creates a new auth middleware with the given configuration and HTTP client
 func NewIntrospectionAuthMiddlewareWithClient(authConfig config.AuthConfig, client oauth.HTTPClient) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -54,15 +198,34 @@ func NewIntrospectionAuthMiddlewareWithClient(authConfig config.AuthConfig, clie
 			}
 
 			// Validate token via introspection and get claims
-			claims, err := oauth.IntrospectToken(token, authConfig, client)
+			claims, err := oauth.IntrospectToken(r.Context(), token, authConfig, client)
 			if err != nil {
 				log.Printf("Token introspection error: %v", err)
+				if errors.Is(err, oauth.ErrIdPUnavailable) {
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			// Store claims in request context
+			// DPoP-bound tokens cannot be used without a proof
+			if claims.Confirmation.JKT != "" {
+				log.Printf("DPoP-bound token presented as Bearer for subject %q", claims.Subject)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			// Certificate-bound tokens require the matching client certificate
+			if err := oauth.VerifyCertificateBinding(claims, r.TLS); err != nil {
+				log.Printf("Certificate binding error for subject %q: %v", claims.Subject, err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			// Store claims and the validated token in request context
 			r = oauth.SetAuthClaims(r, claims)
+			r = oauth.SetAccessToken(r, token)
 
 			// Call the next handler with the enriched request
 			next.ServeHTTP(w, r)
@@ -70,19 +233,45 @@ func NewIntrospectionAuthMiddlewareWithClient(authConfig config.AuthConfig, clie
 	}
 }
 
+// isWriteMethod reports whether the HTTP method modifies state
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Supported authorization schemes
+const (
+	schemeBearer = "Bearer"
+	schemeDPoP   = "DPoP"
+)
+
 // extractBearerToken extracts the Bearer token from the Authorization header
 func extractBearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := extractAuthorization(r)
+	if !ok || scheme != schemeBearer {
+		return "", false
+	}
+	return token, true
+}
+
+// extractAuthorization extracts the scheme and token from the Authorization header
+// Only the Bearer and DPoP schemes are recognized
+func extractAuthorization(r *http.Request) (string, string, bool) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return "", false
+		return "", "", false
 	}
 
-	// Must follow the pattern "Bearer <token>"
+	// Must follow the pattern "<scheme> <token>"
 	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
-		return "", false
+	if len(parts) != 2 || (parts[0] != schemeBearer && parts[0] != schemeDPoP) || parts[1] == "" {
+		return "", "", false
 	}
 
-	// returning only the Token-Part
-	return parts[1], true
+	// returning the scheme and the Token-Part
+	return parts[0], parts[1], true
 }
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -41,7 +44,7 @@ func createValidTokenResponse(requiredScope string) *http.Response {
 		ClientID:  "test-client",
 		Username:  "test-user",
 		TokenType: "Bearer",
-		Exp:       1735689600,
+		Exp:       4102444800,
 		Iat:       1619712000,
 		Nbf:       1619712000,
 		Sub:       "test-subject",
@@ -307,7 +310,7 @@ type MockTokenValidator struct {
 }
 
 // ValidateToken implements the TokenValidator interface
-func (m *MockTokenValidator) ValidateToken(token string) (*oauth.AuthClaims, error) {
+func (m *MockTokenValidator) ValidateToken(ctx context.Context, token string) (*oauth.AuthClaims, error) {
 	return m.ValidateFunc(token)
 }
 
@@ -389,6 +392,104 @@ func TestAuthMiddlewareWithValidator_ValidationError(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareWithValidator_IdPUnavailable(t *testing.T) {
+	var receivedCtx context.Context
+	mockValidator := &mockContextValidator{
+		ValidateFunc: func(ctx context.Context, token string) (*oauth.AuthClaims, error) {
+			receivedCtx = ctx
+			return nil, fmt.Errorf("introspection request failed: %w", oauth.ErrIdPUnavailable)
+		},
+	}
+
+	handler := NewAuthMiddlewareWithValidator(mockValidator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not have been called")
+	}))
+
+	type ctxKey struct{}
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request"))
+	req.Header.Set("Authorization", "Bearer valid-token")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if receivedCtx == nil || receivedCtx.Value(ctxKey{}) != "request" {
+		t.Error("Expected the validator to receive the request context")
+	}
+}
+
+// mockContextValidator is a TokenValidator mock that exposes the context
+type mockContextValidator struct {
+	ValidateFunc func(ctx context.Context, token string) (*oauth.AuthClaims, error)
+}
+
+// ValidateToken implements the TokenValidator interface
+func (m *mockContextValidator) ValidateToken(ctx context.Context, token string) (*oauth.AuthClaims, error) {
+	return m.ValidateFunc(ctx, token)
+}
+
+// MockStrictTokenValidator records which validation path the middleware used
+type MockStrictTokenValidator struct {
+	strictCalls int
+	plainCalls  int
+}
+
+// ValidateToken implements the TokenValidator interface
+func (m *MockStrictTokenValidator) ValidateToken(ctx context.Context, token string) (*oauth.AuthClaims, error) {
+	m.plainCalls++
+	return &oauth.AuthClaims{Subject: "test-subject"}, nil
+}
+
+// ValidateTokenStrict implements the StrictTokenValidator interface
+func (m *MockStrictTokenValidator) ValidateTokenStrict(ctx context.Context, token string) (*oauth.AuthClaims, error) {
+	m.strictCalls++
+	return &oauth.AuthClaims{Subject: "test-subject"}, nil
+}
+
+func TestAuthMiddlewareWithOptions_StrictValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		sensitive  bool
+		wantStrict bool
+	}{
+		{"GET on normal route", http.MethodGet, false, false},
+		{"GET on sensitive route", http.MethodGet, true, true},
+		{"POST", http.MethodPost, false, true},
+		{"PUT", http.MethodPut, false, true},
+		{"PATCH", http.MethodPatch, false, true},
+		{"DELETE", http.MethodDelete, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &MockStrictTokenValidator{}
+			handler := NewAuthMiddlewareWithOptions(validator, AuthnOptions{Sensitive: tt.sensitive})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}),
+			)
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			req.Header.Set("Authorization", "Bearer valid-token")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			if gotStrict := validator.strictCalls == 1 && validator.plainCalls == 0; gotStrict != tt.wantStrict {
+				t.Errorf("strict calls = %d, plain calls = %d, want strict = %v",
+					validator.strictCalls, validator.plainCalls, tt.wantStrict)
+			}
+		})
+	}
+}
+
 func TestExtractBearerToken_EdgeCases(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -490,3 +591,269 @@ func TestAuthMiddleware_HTTPClientNetworkError(t *testing.T) {
 		t.Error("Handler should not have been called")
 	}
 }
+
+// MockDPoPProofVerifier is a mock implementation of the DPoPProofVerifier interface
+type MockDPoPProofVerifier struct {
+	VerifyFunc func(proof, method, requestURL, accessToken string) (*oauth.DPoPProof, error)
+	nonce      string
+}
+
+// VerifyProof implements the DPoPProofVerifier interface
+func (m *MockDPoPProofVerifier) VerifyProof(proof, method, requestURL, accessToken string) (*oauth.DPoPProof, error) {
+	return m.VerifyFunc(proof, method, requestURL, accessToken)
+}
+
+// Nonce implements the DPoPProofVerifier interface
+func (m *MockDPoPProofVerifier) Nonce() string {
+	return m.nonce
+}
+
+func TestAuthMiddlewareWithOptions_DPoP(t *testing.T) {
+	boundClaims := &oauth.AuthClaims{Subject: "user-123", Confirmation: oauth.Confirmation{JKT: "key-thumbprint"}}
+	unboundClaims := &oauth.AuthClaims{Subject: "user-123"}
+
+	verifier := &MockDPoPProofVerifier{
+		nonce: "server-nonce",
+		VerifyFunc: func(proof, method, requestURL, accessToken string) (*oauth.DPoPProof, error) {
+			if requestURL != "https://api.example.com/events" {
+				return nil, fmt.Errorf("%w: unexpected htu %s", oauth.ErrInvalidDPoPProof, requestURL)
+			}
+			switch proof {
+			case "valid-proof":
+				return &oauth.DPoPProof{JKT: "key-thumbprint"}, nil
+			case "other-key-proof":
+				return &oauth.DPoPProof{JKT: "other-thumbprint"}, nil
+			case "no-nonce-proof":
+				return nil, oauth.ErrDPoPNonceRequired
+			default:
+				return nil, oauth.ErrInvalidDPoPProof
+			}
+		},
+	}
+
+	tests := []struct {
+		name           string
+		mode           DPoPMode
+		authorization  string
+		proofs         []string
+		claims         *oauth.AuthClaims
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "DPoP token with valid proof",
+			mode:           DPoPRequired,
+			authorization:  "DPoP bound-token",
+			proofs:         []string{"valid-proof"},
+			claims:         boundClaims,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bearer rejected when DPoP required",
+			mode:           DPoPRequired,
+			authorization:  "Bearer plain-token",
+			claims:         unboundClaims,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Bearer accepted when DPoP allowed",
+			mode:           DPoPAllowed,
+			authorization:  "Bearer plain-token",
+			claims:         unboundClaims,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bound token presented as Bearer",
+			mode:           DPoPAllowed,
+			authorization:  "Bearer bound-token",
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_token",
+		},
+		{
+			name:           "DPoP rejected when disabled",
+			mode:           DPoPDisabled,
+			authorization:  "DPoP bound-token",
+			proofs:         []string{"valid-proof"},
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Missing proof",
+			mode:           DPoPRequired,
+			authorization:  "DPoP bound-token",
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_dpop_proof",
+		},
+		{
+			name:           "Multiple proofs",
+			mode:           DPoPRequired,
+			authorization:  "DPoP bound-token",
+			proofs:         []string{"valid-proof", "valid-proof"},
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_dpop_proof",
+		},
+		{
+			name:           "Proof signed by another key",
+			mode:           DPoPRequired,
+			authorization:  "DPoP bound-token",
+			proofs:         []string{"other-key-proof"},
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_token",
+		},
+		{
+			name:           "Nonce required",
+			mode:           DPoPRequired,
+			authorization:  "DPoP bound-token",
+			proofs:         []string{"no-nonce-proof"},
+			claims:         boundClaims,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "use_dpop_nonce",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &MockTokenValidator{
+				ValidateFunc: func(token string) (*oauth.AuthClaims, error) {
+					return tt.claims, nil
+				},
+			}
+			middleware := NewAuthMiddlewareWithOptions(validator, AuthnOptions{
+				DPoP:         tt.mode,
+				DPoPVerifier: verifier,
+				PublicURL:    "https://api.example.com/",
+			})
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/events?page=2", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			for _, proof := range tt.proofs {
+				req.Header.Add("DPoP", proof)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			challenge := strings.Join(rr.Header().Values("WWW-Authenticate"), "; ")
+			if tt.expectedError != "" && !strings.Contains(challenge, `error="`+tt.expectedError+`"`) {
+				t.Errorf("WWW-Authenticate = %q, want error %q", challenge, tt.expectedError)
+			}
+			if tt.expectedError == "use_dpop_nonce" && rr.Header().Get("DPoP-Nonce") != "server-nonce" {
+				t.Errorf("DPoP-Nonce = %q, want server-nonce", rr.Header().Get("DPoP-Nonce"))
+			}
+		})
+	}
+}
+
+func TestParseDPoPMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    DPoPMode
+		wantErr bool
+	}{
+		{mode: "", want: DPoPDisabled},
+		{mode: "disabled", want: DPoPDisabled},
+		{mode: "allowed", want: DPoPAllowed},
+		{mode: "required", want: DPoPRequired},
+		{mode: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseDPoPMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDPoPMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDPoPMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_CertificateBoundToken(t *testing.T) {
+	// Only the DER bytes matter for the thumbprint
+	clientCert := &x509.Certificate{Raw: []byte("client-certificate")}
+	otherCert := &x509.Certificate{Raw: []byte("other-certificate")}
+	boundClaims := &oauth.AuthClaims{
+		Subject:      "service-account",
+		Confirmation: oauth.Confirmation{X5TS256: oauth.CertificateThumbprint(clientCert)},
+	}
+
+	introspectionBody, _ := json.Marshal(oauth.TokenIntrospectionResponse{
+		Active: true,
+		Sub:    "service-account",
+		Cnf:    boundClaims.Confirmation,
+	})
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(http.StatusOK, string(introspectionBody)), nil
+		},
+	}
+	validator := &MockTokenValidator{
+		ValidateFunc: func(token string) (*oauth.AuthClaims, error) {
+			return boundClaims, nil
+		},
+	}
+
+	middlewares := map[string]func(http.Handler) http.Handler{
+		"Validator":     NewAuthMiddlewareWithValidator(validator),
+		"Introspection": NewIntrospectionAuthMiddlewareWithClient(config.TestConfig(nil).Auth, mockClient),
+	}
+
+	tests := []struct {
+		name           string
+		tls            *tls.ConnectionState
+		expectedStatus int
+	}{
+		{
+			name:           "Matching certificate",
+			tls:            &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Other certificate",
+			tls:            &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "No client certificate",
+			tls:            &tls.ConnectionState{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Plain HTTP",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for middlewareName, middleware := range middlewares {
+		for _, tt := range tests {
+			t.Run(middlewareName+"/"+tt.name, func(t *testing.T) {
+				handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+
+				req := httptest.NewRequest(http.MethodGet, "/events", nil)
+				req.Header.Set("Authorization", "Bearer bound-token")
+				req.TLS = tt.tls
+				rr := httptest.NewRecorder()
+
+				handler.ServeHTTP(rr, req)
+
+				if rr.Code != tt.expectedStatus {
+					t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+				}
+			})
+		}
+	}
+}
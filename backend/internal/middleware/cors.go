@@ -10,6 +10,7 @@ type CORSConfig struct {
 	AllowedOrigins []string
 	AllowedMethods []string
 	AllowedHeaders []string
+	ExposedHeaders []string // response headers readable by browser scripts
 }
 
 // DefaultCORSConfig returns permissive CORS config for development
@@ -17,7 +18,8 @@ func DefaultCORSConfig() CORSConfig {
 	return CORSConfig{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "DPoP"},
+		ExposedHeaders: []string{"DPoP-Nonce", "WWW-Authenticate"},
 	}
 }
 
@@ -32,6 +34,9 @@ func NewCORSMiddleware(config CORSConfig) func(http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
 			w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			if len(config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusOK)
@@ -31,9 +31,10 @@ func TestCORSMiddleware_SetsHeaders(t *testing.T) {
 	}
 
 	expectedHeaders := map[string]string{
-		"Access-Control-Allow-Origin":  "*",
-		"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
-		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Allow-Origin":   "*",
+		"Access-Control-Allow-Methods":  "GET, POST, PUT, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers":  "Content-Type, Authorization, DPoP",
+		"Access-Control-Expose-Headers": "DPoP-Nonce, WWW-Authenticate",
 	}
 
 	for header, expected := range expectedHeaders {
@@ -113,7 +114,7 @@ func TestDefaultCORSConfig(t *testing.T) {
 	if len(config.AllowedMethods) != 5 {
 		t.Errorf("Expected 5 allowed methods, got %d", len(config.AllowedMethods))
 	}
-	if len(config.AllowedHeaders) != 2 {
-		t.Errorf("Expected 2 allowed headers, got %d", len(config.AllowedHeaders))
+	if len(config.AllowedHeaders) != 3 {
+		t.Errorf("Expected 3 allowed headers, got %d", len(config.AllowedHeaders))
 	}
 }
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+)
+
+// PermissionChecker decides whether a token grants a scope on a protected resource
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, token string, claims *oauth.AuthClaims, resource, scope string) (bool, error)
+}
+
+// ResourceScope is a scope of a Keycloak Authorization Services resource
+type ResourceScope struct {
+	Resource string
+	Scope    string
+}
+
+// PolicyEnforcerConfig maps routes to the Keycloak resource scope they require
+type PolicyEnforcerConfig struct {
+	// Permissions maps "<METHOD> <pattern>" to a resource scope, where pattern is the
+	// ServeMux pattern the request matched, e.g. "GET /events/{id}"; unmapped requests are denied
+	Permissions map[string]ResourceScope
+}
+
+// ParsePolicyPermission parses an entry of the form "<METHOD> <pattern>=<resource>#<scope>"
+// and returns its PolicyEnforcerConfig.Permissions key and resource scope
+func ParsePolicyPermission(entry string) (string, ResourceScope, error) {
+	route, permission, ok := strings.Cut(entry, "=")
+	method, pattern, hasPattern := strings.Cut(strings.TrimSpace(route), " ")
+	resource, scope, hasScope := strings.Cut(strings.TrimSpace(permission), "#")
+	if !ok || !hasPattern || !hasScope || method == "" || resource == "" || scope == "" {
+		return "", ResourceScope{}, fmt.Errorf("invalid policy permission %q, want \"<METHOD> <pattern>=<resource>#<scope>\"", entry)
+	}
+	return method + " " + strings.TrimSpace(pattern), ResourceScope{Resource: resource, Scope: scope}, nil
+}
+
+// NewPolicyEnforcerMiddleware creates an authorization middleware that delegates
+// permission decisions to Keycloak Authorization Services
+// Must run after the auth middleware, which provides the token and claims
+func NewPolicyEnforcerMiddleware(checker PermissionChecker, config PolicyEnforcerConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get token and claims from context (set by AuthN middleware)
+			token := oauth.GetAccessToken(r)
+			claims := oauth.GetAuthClaims(r)
+			if token == "" || claims == nil {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			// Deny routes and methods without a mapped resource scope
+			permission, ok := config.Permissions[r.Method+" "+r.Pattern]
+			if !ok {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			granted, err := checker.HasPermission(r.Context(), token, claims, permission.Resource, permission.Scope)
+			if err != nil {
+				log.Printf("Permission check error: %v", err)
+				if errors.Is(err, oauth.ErrIdPUnavailable) {
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			if !granted {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			// Permission granted, call the next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
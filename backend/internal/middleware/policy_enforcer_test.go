@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth"
+)
+
+// MockPermissionChecker is a mock implementation of PermissionChecker
+type MockPermissionChecker struct {
+	HasPermissionFunc func(token string, claims *oauth.AuthClaims, resource, scope string) (bool, error)
+}
+
+func (m *MockPermissionChecker) HasPermission(ctx context.Context, token string, claims *oauth.AuthClaims, resource, scope string) (bool, error) {
+	return m.HasPermissionFunc(token, claims, resource, scope)
+}
+
+func TestPolicyEnforcerMiddleware(t *testing.T) {
+	enforcerConfig := PolicyEnforcerConfig{
+		Permissions: map[string]ResourceScope{
+			"GET /events":       {Resource: "events", Scope: "read"},
+			"DELETE /events":    {Resource: "events", Scope: "delete"},
+			"GET /reports/{id}": {Resource: "reports", Scope: "view"},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		withAuth         bool
+		granted          bool
+		checkErr         error
+		expectedStatus   int
+		expectedResource string
+		expectedScope    string
+	}{
+		{
+			name:             "Granted",
+			method:           http.MethodGet,
+			path:             "/events",
+			withAuth:         true,
+			granted:          true,
+			expectedStatus:   http.StatusOK,
+			expectedResource: "events",
+			expectedScope:    "read",
+		},
+		{
+			name:             "Route with another resource",
+			method:           http.MethodGet,
+			path:             "/reports/42",
+			withAuth:         true,
+			granted:          true,
+			expectedStatus:   http.StatusOK,
+			expectedResource: "reports",
+			expectedScope:    "view",
+		},
+		{
+			name:             "Denied",
+			method:           http.MethodDelete,
+			path:             "/events",
+			withAuth:         true,
+			granted:          false,
+			expectedStatus:   http.StatusForbidden,
+			expectedResource: "events",
+			expectedScope:    "delete",
+		},
+		{
+			name:             "Checker error",
+			method:           http.MethodGet,
+			path:             "/events",
+			withAuth:         true,
+			checkErr:         errors.New("keycloak unavailable"),
+			expectedStatus:   http.StatusForbidden,
+			expectedResource: "events",
+			expectedScope:    "read",
+		},
+		{
+			name:             "Identity provider unavailable",
+			method:           http.MethodGet,
+			path:             "/events",
+			withAuth:         true,
+			checkErr:         oauth.ErrIdPUnavailable,
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedResource: "events",
+			expectedScope:    "read",
+		},
+		{
+			name:           "Unmapped method",
+			method:         http.MethodPost,
+			path:           "/events",
+			withAuth:       true,
+			granted:        true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Unmapped route",
+			method:         http.MethodGet,
+			path:           "/unmapped",
+			withAuth:       true,
+			granted:        true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "No token in context",
+			method:         http.MethodGet,
+			path:           "/events",
+			granted:        true,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotResource, gotScope string
+			checker := &MockPermissionChecker{
+				HasPermissionFunc: func(token string, claims *oauth.AuthClaims, resource, scope string) (bool, error) {
+					gotResource, gotScope = resource, scope
+					return tt.granted, tt.checkErr
+				},
+			}
+
+			handlerCalled := false
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerCalled = true
+				w.WriteHeader(http.StatusOK)
+			})
+			enforcer := NewPolicyEnforcerMiddleware(checker, enforcerConfig)
+			mux := http.NewServeMux()
+			for _, pattern := range []string{"/events", "/reports/{id}", "/unmapped"} {
+				mux.Handle(pattern, enforcer(testHandler))
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.withAuth {
+				req = oauth.SetAccessToken(req, "access-token")
+				req = oauth.SetAuthClaims(req, &oauth.AuthClaims{Subject: "user-123"})
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if handlerCalled != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("handlerCalled = %v, want %v", handlerCalled, tt.expectedStatus == http.StatusOK)
+			}
+			if gotResource != tt.expectedResource || gotScope != tt.expectedScope {
+				t.Errorf("permission = %q#%q, want %q#%q", gotResource, gotScope, tt.expectedResource, tt.expectedScope)
+			}
+		})
+	}
+}
+
+func TestParsePolicyPermission(t *testing.T) {
+	tests := []struct {
+		entry     string
+		wantRoute string
+		wantScope ResourceScope
+		wantErr   bool
+	}{
+		{entry: "GET /events/{id}=events#read", wantRoute: "GET /events/{id}", wantScope: ResourceScope{Resource: "events", Scope: "read"}},
+		{entry: " DELETE /events = archive#purge ", wantRoute: "DELETE /events", wantScope: ResourceScope{Resource: "archive", Scope: "purge"}},
+		{entry: "/events=events#read", wantErr: true},
+		{entry: "GET /events=events", wantErr: true},
+		{entry: "GET /events", wantErr: true},
+		{entry: "GET /events=#read", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			route, scope, err := ParsePolicyPermission(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicyPermission() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if route != tt.wantRoute || scope != tt.wantScope {
+				t.Errorf("ParsePolicyPermission() = %q, %+v; want %q, %+v", route, scope, tt.wantRoute, tt.wantScope)
+			}
+		})
+	}
+}
@@ -1,9 +1,20 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// MaxTitleLength is the maximum number of characters allowed in an event title,
+// matching the VARCHAR(255) column in events.events
+const MaxTitleLength = 255
+
+// MaxLocationLength is the maximum number of characters allowed in an event location,
+// matching the VARCHAR(255) column in events.events
+const MaxLocationLength = 255
+
 // Event represents an event in the system
 type Event struct {
 	ID          string    `json:"id"`
@@ -11,7 +22,45 @@ type Event struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Location    string    `json:"location"`
+
+	// Organization is the alias of the Keycloak organization the event belongs to
+	// It is assigned by the server and cannot be changed
+	Organization string `json:"organization"`
 }
 
 // Events is a slice of Event
 type Events []Event
+
+// ValidationErrors maps a field name to a human-readable validation message
+type ValidationErrors map[string]string
+
+// Validate checks the event fields against the database constraints
+// Returns nil if the event is valid
+func (e *Event) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	title := strings.TrimSpace(e.Title)
+	switch {
+	case title == "":
+		errs["title"] = "title is required"
+	case utf8.RuneCountInString(e.Title) > MaxTitleLength:
+		errs["title"] = fmt.Sprintf("title must be at most %d characters", MaxTitleLength)
+	}
+
+	if e.Date.IsZero() {
+		errs["date"] = "date is required"
+	}
+
+	location := strings.TrimSpace(e.Location)
+	switch {
+	case location == "":
+		errs["location"] = "location is required"
+	case utf8.RuneCountInString(e.Location) > MaxLocationLength:
+		errs["location"] = fmt.Sprintf("location must be at most %d characters", MaxLocationLength)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
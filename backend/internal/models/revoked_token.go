@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxJTILength is the maximum number of characters allowed in a token ID,
+// matching the VARCHAR(255) column in events.revoked_tokens
+const MaxJTILength = 255
+
+// RevokedToken is an access token that must no longer be accepted, identified by its jti claim
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"` // the token's exp; the entry can be purged afterwards
+	Reason    string    `json:"reason"`
+	RevokedBy string    `json:"revoked_by,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// Validate checks the revocation fields against the database constraints
+// Returns nil if the revocation is valid
+func (t *RevokedToken) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	jti := strings.TrimSpace(t.JTI)
+	switch {
+	case jti == "":
+		errs["jti"] = "jti is required"
+	case utf8.RuneCountInString(t.JTI) > MaxJTILength:
+		errs["jti"] = fmt.Sprintf("jti must be at most %d characters", MaxJTILength)
+	}
+
+	if t.ExpiresAt.IsZero() {
+		errs["expires_at"] = "expires_at is required"
+	}
+
+	if strings.TrimSpace(t.Reason) == "" {
+		errs["reason"] = "reason is required"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
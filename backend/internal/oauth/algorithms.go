@@ -0,0 +1,48 @@
+package oauth
+
+import (
+	"fmt"
+	"slices"
+)
+
+// supportedAlgorithms are the asymmetric JWS algorithms accepted for access tokens
+// Symmetric (HS*) and "none" are never accepted: the verification keys are public
+var supportedAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// defaultAlgorithms are allowed when neither the configuration nor the discovery
+// document name any; they match Keycloak's default RSA realm keys
+var defaultAlgorithms = []string{"RS256", "RS384", "RS512"}
+
+// IsSupportedAlgorithm reports whether alg can be used to verify access tokens
+func IsSupportedAlgorithm(alg string) bool {
+	return slices.Contains(supportedAlgorithms, alg)
+}
+
+// resolveAlgorithms returns the algorithms a JWKSValidator accepts
+// Configured algorithms take precedence and must all be supported; otherwise the
+// supported subset of the algorithms advertised in the discovery document is used,
+// falling back to defaultAlgorithms
+func resolveAlgorithms(configured, advertised []string) ([]string, error) {
+	if len(configured) > 0 {
+		for _, alg := range configured {
+			if !IsSupportedAlgorithm(alg) {
+				return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+			}
+		}
+		return configured, nil
+	}
+
+	// Keycloak advertises HS* as well, which must not be accepted
+	algorithms := slices.DeleteFunc(slices.Clone(advertised), func(alg string) bool {
+		return !IsSupportedAlgorithm(alg)
+	})
+	if len(algorithms) == 0 {
+		return defaultAlgorithms, nil
+	}
+	return algorithms, nil
+}
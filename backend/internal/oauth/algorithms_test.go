@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+func TestResolveAlgorithms(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []string
+		advertised []string
+		want       []string
+		wantErr    bool
+	}{
+		{name: "Defaults", want: defaultAlgorithms},
+		{name: "Configured", configured: []string{"ES256", "EdDSA"}, advertised: []string{"RS256"}, want: []string{"ES256", "EdDSA"}},
+		{name: "Configured symmetric algorithm", configured: []string{"HS256"}, wantErr: true},
+		{name: "Configured none", configured: []string{"none"}, wantErr: true},
+		{name: "Advertised", advertised: []string{"HS256", "ES384", "PS256", "none"}, want: []string{"ES384", "PS256"}},
+		{name: "Only symmetric algorithms advertised", advertised: []string{"HS256", "HS512"}, want: defaultAlgorithms},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveAlgorithms(tt.configured, tt.advertised)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveAlgorithms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveAlgorithms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJWKSValidator_UnsupportedAlgorithm(t *testing.T) {
+	_, err := NewJWKSValidator(context.Background(), "http://localhost:1/certs", "https://issuer",
+		WithAllowedAlgorithms("RS256", "HS256"))
+	if err == nil {
+		t.Error("NewJWKSValidator() error = nil, want error for HS256")
+	}
+}
+
+// TestJWKSValidator_KeyTypes validates tokens signed with keys of each supported type,
+// with the allowed algorithms taken from the discovery document
+func TestJWKSValidator_KeyTypes(t *testing.T) {
+	for _, alg := range oauthtest.Algorithms {
+		t.Run(alg, func(t *testing.T) {
+			realm := oauthtest.NewServer("events")
+			defer realm.Close()
+			realm.UseAlgorithm(alg)
+
+			authConfig := realm.AuthConfig()
+			authConfig.DiscoveryURL = realm.Issuer() + "/.well-known/openid-configuration"
+			validator, err := NewJWKSValidatorFromConfig(context.Background(), authConfig, nil)
+			if err != nil {
+				t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+			}
+
+			claims, err := validator.ValidateToken(context.Background(), realm.MintToken(oauthtest.TokenOptions{Subject: "user-123"}))
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.Subject != "user-123" {
+				t.Errorf("Subject = %q, want user-123", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_AlgorithmNotAllowed(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	realm.UseAlgorithm("ES256")
+
+	authConfig := realm.AuthConfig()
+	authConfig.AllowedAlgorithms = []string{"RS256", "EdDSA"}
+	validator, err := NewJWKSValidatorFromConfig(context.Background(), authConfig, nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+	}
+
+	if _, err := validator.ValidateToken(context.Background(), realm.MintToken(oauthtest.TokenOptions{})); err == nil {
+		t.Error("ValidateToken() of an ES256 token error = nil, want error")
+	}
+}
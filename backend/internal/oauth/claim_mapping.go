@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// ClaimMapping declares where the identity fields of AuthClaims are read from
+// It applies alike to JWT payloads and introspection responses
+//
+// Paths are JSON pointers (RFC 6901), e.g. "/realm_access/roles", with one extension:
+// a "*" segment matches every member of an object or element of an array. Strings
+// found below an object member matched by "*" are prefixed with the member name and
+// ClientRoleSeparator, so "/resource_access/*/roles" yields "<client>:<role>"
+type ClaimMapping struct {
+	Subject  []string // first value found wins
+	Username []string // first value found wins
+	Email    []string // first value found wins
+	Roles    []string // values of all paths are merged
+	Groups   []string // values of all paths are merged
+
+	Organizations []string // values of all paths are merged, see parseOrganizations
+
+	Attributes map[string]string // attribute name -> path, copied into AuthClaims.Attributes
+}
+
+// DefaultClaimMapping returns the mapping for Keycloak's default protocol mappers
+func DefaultClaimMapping() ClaimMapping {
+	return ClaimMapping{
+		Subject:  []string{"/sub"},
+		Username: []string{"/preferred_username", "/username"},
+		Email:    []string{"/email"},
+		Roles:    []string{"/realm_access/roles", "/resource_access/*/roles"},
+		Groups:   []string{"/groups"},
+
+		Organizations: []string{"/organization"},
+	}
+}
+
+// NewClaimMapping creates the ClaimMapping configured in AuthConfig
+// Fields that are not configured keep the DefaultClaimMapping paths
+func NewClaimMapping(authConfig config.AuthConfig) (ClaimMapping, error) {
+	mapping := DefaultClaimMapping()
+	for _, field := range []struct {
+		configured []string
+		target     *[]string
+	}{
+		{authConfig.ClaimSubject, &mapping.Subject},
+		{authConfig.ClaimUsername, &mapping.Username},
+		{authConfig.ClaimEmail, &mapping.Email},
+		{authConfig.ClaimRoles, &mapping.Roles},
+		{authConfig.ClaimGroups, &mapping.Groups},
+		{authConfig.ClaimOrganizations, &mapping.Organizations},
+	} {
+		if len(field.configured) > 0 {
+			*field.target = field.configured
+		}
+	}
+
+	for _, entry := range authConfig.ClaimAttributes {
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" {
+			return ClaimMapping{}, fmt.Errorf("invalid claim attribute %q, want <name>=<path>", entry)
+		}
+		if mapping.Attributes == nil {
+			mapping.Attributes = make(map[string]string)
+		}
+		mapping.Attributes[name] = path
+	}
+
+	if err := mapping.validate(); err != nil {
+		return ClaimMapping{}, err
+	}
+	return mapping, nil
+}
+
+// validate checks that every path is a JSON pointer
+func (m ClaimMapping) validate() error {
+	paths := [][]string{m.Subject, m.Username, m.Email, m.Roles, m.Groups, m.Organizations}
+	for _, path := range m.Attributes {
+		paths = append(paths, []string{path})
+	}
+	for _, list := range paths {
+		for _, path := range list {
+			if !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("invalid claim path %q, must start with /", path)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeClaims decodes a JSON object for use with ClaimMapping.apply
+func decodeClaims(data []byte) (map[string]any, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// apply sets the mapped identity fields of claims from the payload
+func (m ClaimMapping) apply(payload map[string]any, claims *AuthClaims) {
+	claims.Subject = firstString(payload, m.Subject)
+	claims.Username = firstString(payload, m.Username)
+	claims.Email = firstString(payload, m.Email)
+	claims.Roles = allStrings(payload, m.Roles)
+	claims.Groups = allStrings(payload, m.Groups)
+	claims.Organizations = allOrganizations(payload, m.Organizations)
+
+	for name, path := range m.Attributes {
+		matches := lookupPath(payload, path)
+		if len(matches) == 0 {
+			continue
+		}
+		if claims.Attributes == nil {
+			claims.Attributes = make(map[string]any, len(m.Attributes))
+		}
+		claims.Attributes[name] = matches[0].value
+	}
+}
+
+// firstString returns the first non-empty string found at the given paths
+func firstString(payload map[string]any, paths []string) string {
+	for _, path := range paths {
+		for _, match := range lookupPath(payload, path) {
+			if s, ok := match.value.(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// allStrings returns the strings found at all given paths; arrays are flattened
+func allStrings(payload map[string]any, paths []string) []string {
+	var values []string
+	for _, path := range paths {
+		for _, match := range lookupPath(payload, path) {
+			for _, s := range stringValues(match.value) {
+				if match.prefix != "" {
+					s = match.prefix + ClientRoleSeparator + s
+				}
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// stringValues returns a string, or the strings of an array
+func stringValues(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var values []string
+		for _, element := range v {
+			if s, ok := element.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// pathMatch is a value found by lookupPath
+type pathMatch struct {
+	value  any
+	prefix string // name of the object member matched by the last "*" segment, if any
+}
+
+// lookupPath returns the values at a JSON pointer path, see ClaimMapping
+func lookupPath(payload map[string]any, path string) []pathMatch {
+	matches := []pathMatch{{value: payload}}
+	for _, segment := range strings.Split(path, "/")[1:] {
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+
+		var next []pathMatch
+		for _, match := range matches {
+			switch node := match.value.(type) {
+			case map[string]any:
+				if segment != "*" {
+					if child, ok := node[segment]; ok {
+						next = append(next, pathMatch{value: child, prefix: match.prefix})
+					}
+					continue
+				}
+				keys := make([]string, 0, len(node))
+				for key := range node {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					next = append(next, pathMatch{value: node[key], prefix: key})
+				}
+			case []any:
+				if segment == "*" {
+					for _, element := range node {
+						next = append(next, pathMatch{value: element, prefix: match.prefix})
+					}
+					continue
+				}
+				if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(node) {
+					next = append(next, pathMatch{value: node[i], prefix: match.prefix})
+				}
+			}
+		}
+		matches = next
+	}
+	return matches
+}
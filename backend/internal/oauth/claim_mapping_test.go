@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+func TestLookupPath(t *testing.T) {
+	payload, _ := decodeClaims([]byte(`{
+		"sub": "user-123",
+		"a/b": {"c~d": "escaped"},
+		"list": ["first", "second"],
+		"resource_access": {
+			"events-api": {"roles": ["org-maintainer"]},
+			"account": {"roles": ["view-profile"]}
+		},
+		"teams": [{"name": "red"}, {"name": "blue"}]
+	}`))
+
+	tests := []struct {
+		path       string
+		wantValues []any
+		wantPrefix []string
+	}{
+		{path: "/sub", wantValues: []any{"user-123"}, wantPrefix: []string{""}},
+		{path: "/a~1b/c~0d", wantValues: []any{"escaped"}, wantPrefix: []string{""}},
+		{path: "/list/1", wantValues: []any{"second"}, wantPrefix: []string{""}},
+		{path: "/list/2"},
+		{path: "/missing/path"},
+		{path: "/sub/nested"},
+		{
+			path:       "/resource_access/*/roles",
+			wantValues: []any{[]any{"view-profile"}, []any{"org-maintainer"}},
+			wantPrefix: []string{"account", "events-api"},
+		},
+		{path: "/teams/*/name", wantValues: []any{"red", "blue"}, wantPrefix: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var values []any
+			var prefixes []string
+			for _, match := range lookupPath(payload, tt.path) {
+				values = append(values, match.value)
+				prefixes = append(prefixes, match.prefix)
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("values = %v, want %v", values, tt.wantValues)
+			}
+			if !reflect.DeepEqual(prefixes, tt.wantPrefix) {
+				t.Errorf("prefixes = %v, want %v", prefixes, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestNewClaimMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		authConfig config.AuthConfig
+		want       ClaimMapping
+		wantErr    bool
+	}{
+		{name: "Defaults", want: DefaultClaimMapping()},
+		{
+			name: "Overrides",
+			authConfig: config.AuthConfig{
+				ClaimRoles:      []string{"/roles", "/ext/roles"},
+				ClaimAttributes: []string{"tenant=/ext/tenant_id", " department = /department "},
+			},
+			want: func() ClaimMapping {
+				m := DefaultClaimMapping()
+				m.Roles = []string{"/roles", "/ext/roles"}
+				m.Attributes = map[string]string{"tenant": "/ext/tenant_id", "department": "/department"}
+				return m
+			}(),
+		},
+		{name: "Attribute without path", authConfig: config.AuthConfig{ClaimAttributes: []string{"tenant"}}, wantErr: true},
+		{name: "Path without leading slash", authConfig: config.AuthConfig{ClaimSubject: []string{"sub"}}, wantErr: true},
+		{name: "Attribute path without leading slash", authConfig: config.AuthConfig{ClaimAttributes: []string{"tenant=ext"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClaimMapping(tt.authConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClaimMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewClaimMapping() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClaimMapping_BothValidators checks that introspection responses and JWT payloads
+// are mapped alike
+func TestClaimMapping_BothValidators(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+
+	token := realm.MintToken(oauthtest.TokenOptions{
+		Subject: "user-123",
+		Roles:   []string{"org-user"},
+		Extra: map[string]any{
+			"upn":    "alice@corp.example",
+			"mail":   "alice@example.com",
+			"groups": []string{"/staff", "/staff/events"},
+			"ext":    map[string]any{"roles": []string{"event-admin"}, "tenant_id": "acme", "level": 3},
+		},
+	})
+
+	authConfig := realm.AuthConfig()
+	authConfig.ClaimUsername = []string{"/upn"}
+	authConfig.ClaimEmail = []string{"/missing", "/mail"}
+	authConfig.ClaimRoles = []string{"/realm_access/roles", "/ext/roles"}
+	authConfig.ClaimAttributes = []string{"tenant=/ext/tenant_id", "level=/ext/level", "absent=/nope"}
+
+	want := &AuthClaims{
+		Subject:    "user-123",
+		Username:   "alice@corp.example",
+		Email:      "alice@example.com",
+		Roles:      []string{"org-user", "event-admin"},
+		Groups:     []string{"/staff", "/staff/events"},
+		Attributes: map[string]any{"tenant": "acme", "level": float64(3)},
+	}
+
+	for _, method := range []ValidationMethod{ValidationMethodIntrospection, ValidationMethodJWKS} {
+		t.Run(string(method), func(t *testing.T) {
+			validator, err := NewTokenValidator(method, ValidatorConfig{AuthConfig: authConfig, Context: context.Background()})
+			if err != nil {
+				t.Fatalf("NewTokenValidator() error = %v", err)
+			}
+
+			claims, err := validator.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			got := &AuthClaims{
+				Subject:    claims.Subject,
+				Username:   claims.Username,
+				Email:      claims.Email,
+				Roles:      claims.Roles,
+				Groups:     claims.Groups,
+				Attributes: claims.Attributes,
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("mapped claims = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"slices"
+	"strings"
+	"time"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -12,13 +14,57 @@ type contextKey string
 // authClaimsKey is the context key for storing AuthClaims
 const authClaimsKey contextKey = "authClaims"
 
+// accessTokenKey is the context key for storing the raw, validated access token
+const accessTokenKey contextKey = "accessToken"
+
 // AuthClaims represents the authenticated user's claims extracted from the token
 type AuthClaims struct {
 	Subject  string   // sub claim - unique user identifier
 	Username string   // preferred_username claim
 	Email    string   // email claim
 	Scopes   []string // scope claim - space-separated scopes from token
-	Roles    []string // realm_access.roles or resource_access roles
+	Roles    []string // realm_access.roles plus resource_access roles as "<client>:<role>"
+	Groups   []string // groups claim - group memberships
+
+	Organizations []Organization // organization claim - Keycloak organization memberships
+
+	Attributes map[string]any // custom claims selected by ClaimMapping.Attributes, nil if none
+
+	Issuer string // iss claim - the token's issuer
+	Realm  string // Keycloak realm derived from the issuer, empty for other identity providers
+
+	TokenID   string // jti claim - unique token identifier
+	SessionID string // sid claim - the Keycloak user session the token belongs to
+
+	Confirmation Confirmation // cnf claim - the key or certificate a sender-constrained token is bound to
+	Actor        *Actor       // act claim - the party acting on behalf of the subject, nil if not delegated
+
+	IssuedAt  time.Time // iat claim - zero if the token carries no issue time
+	ExpiresAt time.Time // exp claim - zero if the token carries no expiry
+}
+
+// Confirmation represents the cnf claim binding a token to a key (RFC 7800)
+type Confirmation struct {
+	JKT     string `json:"jkt,omitempty"`      // SHA-256 JWK thumbprint of the DPoP key (RFC 9449)
+	X5TS256 string `json:"x5t#S256,omitempty"` // SHA-256 thumbprint of the client certificate (RFC 8705)
+}
+
+// Actor represents the act claim of a delegated token (RFC 8693, section 4.1)
+// Nested actors are prior actors in the delegation chain
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Issuer   string `json:"iss,omitempty"`
+	Actor    *Actor `json:"act,omitempty"`
+}
+
+// ClientRoleSeparator separates the client ID from the role name in namespaced client roles,
+// e.g. "events-api:org-maintainer"
+const ClientRoleSeparator = ":"
+
+// KeycloakRoles represents Keycloak's realm_access and resource_access.<client> claim objects
+type KeycloakRoles struct {
+	Roles []string `json:"roles"`
 }
 
 // TokenIntrospectionResponse represents the response from Keycloak's token introspection endpoint
@@ -35,6 +81,24 @@ type TokenIntrospectionResponse struct {
 	Aud       []string `json:"aud,omitempty"`
 	Iss       string   `json:"iss,omitempty"`
 	Jti       string   `json:"jti,omitempty"`
+	Sid       string   `json:"sid,omitempty"`
+
+	Cnf Confirmation `json:"cnf"`
+	Act *Actor       `json:"act,omitempty"`
+
+	Email          string                   `json:"email,omitempty"`
+	RealmAccess    KeycloakRoles            `json:"realm_access"`
+	ResourceAccess map[string]KeycloakRoles `json:"resource_access,omitempty"`
+}
+
+// RealmFromIssuer returns the Keycloak realm name of an issuer URL such as
+// "http://localhost:8081/realms/events", or "" if the issuer is not a Keycloak realm
+func RealmFromIssuer(issuer string) string {
+	_, realm, ok := strings.Cut(issuer, "/realms/")
+	if !ok || realm == "" || strings.Contains(realm, "/") {
+		return ""
+	}
+	return realm
 }
 
 // HasScope checks if the claims contain a specific scope
@@ -93,6 +157,76 @@ func (c *AuthClaims) HasAllRoles(roles ...string) bool {
 	return true
 }
 
+// ActorChain returns the actors of a delegated token, the current actor first
+// It returns nil if the token was not obtained through delegation
+func (c *AuthClaims) ActorChain() []Actor {
+	var chain []Actor
+	for actor := c.Actor; actor != nil; actor = actor.Actor {
+		chain = append(chain, *actor)
+	}
+	return chain
+}
+
+// clone returns a deep copy of the claims, so cached or shared claims can be handed to
+// callers that modify them
+func (c *AuthClaims) clone() *AuthClaims {
+	if c == nil {
+		return nil
+	}
+	cloned := *c
+	cloned.Scopes = slices.Clone(c.Scopes)
+	cloned.Roles = slices.Clone(c.Roles)
+	cloned.Groups = slices.Clone(c.Groups)
+
+	if c.Organizations != nil {
+		cloned.Organizations = make([]Organization, len(c.Organizations))
+		for i, org := range c.Organizations {
+			if org.Attributes != nil {
+				attributes := make(map[string][]string, len(org.Attributes))
+				for name, values := range org.Attributes {
+					attributes[name] = slices.Clone(values)
+				}
+				org.Attributes = attributes
+			}
+			cloned.Organizations[i] = org
+		}
+	}
+
+	if c.Attributes != nil {
+		cloned.Attributes = cloneClaimValue(c.Attributes).(map[string]any)
+	}
+
+	// Actors are immutable in practice, but copying the short chain keeps clone deep
+	if c.Actor != nil {
+		actor := *c.Actor
+		cloned.Actor = &actor
+		for prev := cloned.Actor; prev.Actor != nil; prev = prev.Actor {
+			next := *prev.Actor
+			prev.Actor = &next
+		}
+	}
+	return &cloned
+}
+
+// cloneClaimValue deep-copies a decoded JSON value
+func cloneClaimValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		cloned := make(map[string]any, len(v))
+		for key, element := range v {
+			cloned[key] = cloneClaimValue(element)
+		}
+		return cloned
+	case []any:
+		cloned := make([]any, len(v))
+		for i, element := range v {
+			cloned[i] = cloneClaimValue(element)
+		}
+		return cloned
+	}
+	return value
+}
+
 // GetAuthClaims retrieves AuthClaims from the request context
 // Returns nil if no claims are present or if the value is not of type *AuthClaims
 func GetAuthClaims(r *http.Request) *AuthClaims {
@@ -109,3 +243,17 @@ func SetAuthClaims(r *http.Request, claims *AuthClaims) *http.Request {
 	ctx := context.WithValue(r.Context(), authClaimsKey, claims)
 	return r.WithContext(ctx)
 }
+
+// GetAccessToken retrieves the raw access token validated by the auth middleware
+// Returns an empty string if no token is present
+func GetAccessToken(r *http.Request) string {
+	token, _ := r.Context().Value(accessTokenKey).(string)
+	return token
+}
+
+// SetAccessToken stores the raw access token in the request context and returns a new request
+// with the enriched context
+func SetAccessToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), accessTokenKey, token)
+	return r.WithContext(ctx)
+}
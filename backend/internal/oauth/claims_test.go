@@ -2,8 +2,10 @@ package oauth
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -355,3 +357,94 @@ func TestGetSetAuthClaims(t *testing.T) {
 		}
 	})
 }
+
+func TestAuthClaims_Clone(t *testing.T) {
+	newClaims := func() *AuthClaims {
+		return &AuthClaims{
+			Subject: "user-123",
+			Scopes:  []string{"events:read"},
+			Roles:   []string{"org-user"},
+			Groups:  []string{"/staff"},
+			Organizations: []Organization{
+				{Alias: "acme", ID: "org-1", Attributes: map[string][]string{"region": {"eu"}}},
+			},
+			Attributes: map[string]any{"ext": map[string]any{"tags": []any{"a"}}},
+			Actor:      &Actor{Subject: "service-a", Actor: &Actor{Subject: "service-b"}},
+		}
+	}
+	original := newClaims()
+	cloned := original.clone()
+	if !reflect.DeepEqual(cloned, original) {
+		t.Fatalf("clone() = %+v, want %+v", cloned, original)
+	}
+
+	cloned.Scopes[0] = "changed"
+	cloned.Roles[0] = "system-admin"
+	cloned.Groups[0] = "changed"
+	cloned.Organizations[0].Alias = "changed"
+	cloned.Organizations[0].Attributes["region"][0] = "us"
+	cloned.Attributes["ext"].(map[string]any)["tags"].([]any)[0] = "changed"
+	cloned.Actor.Actor.Subject = "changed"
+
+	if !reflect.DeepEqual(original, newClaims()) {
+		t.Errorf("original = %+v after mutating the clone, want unchanged", original)
+	}
+	if (*AuthClaims)(nil).clone() != nil {
+		t.Error("clone() of nil claims is not nil")
+	}
+}
+
+// TestKeycloakRoles checks how the default claim mapping flattens Keycloak's role claims
+func TestKeycloakRoles(t *testing.T) {
+	tests := []struct {
+		name           string
+		realmAccess    KeycloakRoles
+		resourceAccess map[string]KeycloakRoles
+		want           []string
+	}{
+		{
+			name: "no roles",
+			want: nil,
+		},
+		{
+			name:        "realm roles only",
+			realmAccess: KeycloakRoles{Roles: []string{"system-admin", "org-user"}},
+			want:        []string{"system-admin", "org-user"},
+		},
+		{
+			name: "client roles are namespaced and sorted by client",
+			resourceAccess: map[string]KeycloakRoles{
+				"events-api": {Roles: []string{"org-maintainer"}},
+				"account":    {Roles: []string{"manage-account", "view-profile"}},
+			},
+			want: []string{"account:manage-account", "account:view-profile", "events-api:org-maintainer"},
+		},
+		{
+			name:        "realm and client role with the same name stay distinct",
+			realmAccess: KeycloakRoles{Roles: []string{"org-maintainer"}},
+			resourceAccess: map[string]KeycloakRoles{
+				"events-api": {Roles: []string{"org-maintainer"}},
+			},
+			want: []string{"org-maintainer", "events-api:org-maintainer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(TokenIntrospectionResponse{RealmAccess: tt.realmAccess, ResourceAccess: tt.resourceAccess})
+			payload, _ := decodeClaims(data)
+			var claims AuthClaims
+			DefaultClaimMapping().apply(payload, &claims)
+
+			got := claims.Roles
+			if len(got) != len(tt.want) {
+				t.Fatalf("Roles = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Roles[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// Client authentication methods for the token and introspection endpoints
+const (
+	ClientAuthSecretPost    = "client_secret_post"
+	ClientAuthSecretBasic   = "client_secret_basic"
+	ClientAuthPrivateKeyJWT = "private_key_jwt"
+)
+
+// clientAssertionType identifies a JWT client assertion (RFC 7523, section 2.2)
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// clientAssertionLifetime is the validity of a client assertion
+const clientAssertionLifetime = time.Minute
+
+// ClientAuthenticator authenticates the backend as AuthConfig.ClientID to Keycloak
+type ClientAuthenticator interface {
+	// Authenticate adds credentials to the form or header of a request to endpoint
+	Authenticate(form url.Values, header http.Header, endpoint string) error
+}
+
+// NewClientAuthenticator creates the ClientAuthenticator selected by AuthConfig.ClientAuthMethod
+// For private_key_jwt the key file is read here, so callers create the authenticator
+// once and reuse it for every request
+func NewClientAuthenticator(authConfig config.AuthConfig) (ClientAuthenticator, error) {
+	switch authConfig.ClientAuthMethod {
+	case "", ClientAuthSecretPost:
+		return &clientSecretPost{clientID: authConfig.ClientID, clientSecret: authConfig.ClientSecret}, nil
+	case ClientAuthSecretBasic:
+		return &clientSecretBasic{clientID: authConfig.ClientID, clientSecret: authConfig.ClientSecret}, nil
+	case ClientAuthPrivateKeyJWT:
+		key, err := loadClientKey(authConfig.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return newPrivateKeyJWT(authConfig.ClientID, authConfig.ClientKeyID, key)
+	default:
+		return nil, fmt.Errorf("unsupported client authentication method: %s", authConfig.ClientAuthMethod)
+	}
+}
+
+// newClientAuthRequest creates an authenticated form POST request to a Keycloak endpoint
+func newClientAuthRequest(ctx context.Context, endpoint string, form url.Values, authenticator ClientAuthenticator) (*http.Request, error) {
+	header := make(http.Header)
+	if err := authenticator.Authenticate(form, header, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to authenticate client: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// clientSecretPost sends the client credentials in the form body
+type clientSecretPost struct {
+	clientID     string
+	clientSecret string
+}
+
+// Authenticate implements the ClientAuthenticator interface
+func (a *clientSecretPost) Authenticate(form url.Values, header http.Header, endpoint string) error {
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	return nil
+}
+
+// clientSecretBasic sends the client credentials with HTTP Basic authentication
+type clientSecretBasic struct {
+	clientID     string
+	clientSecret string
+}
+
+// Authenticate implements the ClientAuthenticator interface
+// Both values are form-encoded before being joined (RFC 6749, section 2.3.1)
+func (a *clientSecretBasic) Authenticate(form url.Values, header http.Header, endpoint string) error {
+	credentials := url.QueryEscape(a.clientID) + ":" + url.QueryEscape(a.clientSecret)
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	return nil
+}
+
+// privateKeyJWT authenticates with a JWT signed by the client's private key (RFC 7523)
+type privateKeyJWT struct {
+	clientID string
+	keyID    string
+	key      crypto.Signer
+	method   jwt.SigningMethod
+	now      func() time.Time
+}
+
+// newPrivateKeyJWT creates a privateKeyJWT, choosing the signing algorithm from the key
+func newPrivateKeyJWT(clientID, keyID string, key crypto.Signer) (*privateKeyJWT, error) {
+	var method jwt.SigningMethod
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			method = jwt.SigningMethodES256
+		case 384:
+			method = jwt.SigningMethodES384
+		case 521:
+			method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %s", k.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported client key type %T", key)
+	}
+	return &privateKeyJWT{clientID: clientID, keyID: keyID, key: key, method: method, now: time.Now}, nil
+}
+
+// Authenticate implements the ClientAuthenticator interface
+// The assertion's audience is the endpoint it is sent to
+func (a *privateKeyJWT) Authenticate(form url.Values, header http.Header, endpoint string) error {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return err
+	}
+
+	now := a.now()
+	token := jwt.NewWithClaims(a.method, jwt.RegisteredClaims{
+		Issuer:    a.clientID,
+		Subject:   a.clientID,
+		Audience:  jwt.ClaimStrings{endpoint},
+		ID:        base64.RawURLEncoding.EncodeToString(jti),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(clientAssertionLifetime)),
+	})
+	if a.keyID != "" {
+		token.Header["kid"] = a.keyID
+	}
+	assertion, err := token.SignedString(a.key)
+	if err != nil {
+		return fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+
+	form.Set("client_id", a.clientID)
+	form.Set("client_assertion_type", clientAssertionType)
+	form.Set("client_assertion", assertion)
+	return nil
+}
+
+// loadClientKey reads a PEM-encoded RSA or EC private key
+// PKCS#8, PKCS#1 ("RSA PRIVATE KEY") and SEC 1 ("EC PRIVATE KEY") encodings are accepted
+func loadClientKey(path string) (crypto.Signer, error) {
+	if path == "" {
+		return nil, errors.New("private_key_jwt requires a client key file")
+	}
+	keyPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client key file: %w", err)
+	}
+	key, err := parseClientKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// parseClientKey parses the first PEM block as a private key
+func parseClientKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
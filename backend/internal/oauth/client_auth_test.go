@@ -0,0 +1,233 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// writeClientKey writes key as a PKCS#8 PEM file and returns its path
+func writeClientKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "client-key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestClientSecretAuthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		wantForm   url.Values
+		wantHeader string
+	}{
+		{
+			name:     "client_secret_post",
+			method:   ClientAuthSecretPost,
+			wantForm: url.Values{"client_id": {"events-api"}, "client_secret": {"s3cr:et&"}},
+		},
+		{
+			name:     "Default",
+			wantForm: url.Values{"client_id": {"events-api"}, "client_secret": {"s3cr:et&"}},
+		},
+		{
+			name:     "client_secret_basic",
+			method:   ClientAuthSecretBasic,
+			wantForm: url.Values{},
+			// base64("events-api:s3cr%3Aet%26")
+			wantHeader: "Basic ZXZlbnRzLWFwaTpzM2NyJTNBZXQlMjY=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := NewClientAuthenticator(config.AuthConfig{
+				ClientID:         "events-api",
+				ClientSecret:     "s3cr:et&",
+				ClientAuthMethod: tt.method,
+			})
+			if err != nil {
+				t.Fatalf("NewClientAuthenticator() error = %v", err)
+			}
+
+			form := url.Values{}
+			header := make(http.Header)
+			if err := authenticator.Authenticate(form, header, testTokenURL); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if form.Encode() != tt.wantForm.Encode() {
+				t.Errorf("form = %v, want %v", form, tt.wantForm)
+			}
+			if got := header.Get("Authorization"); got != tt.wantHeader {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyJWTAuthentication(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		key        crypto.Signer
+		publicKey  crypto.PublicKey
+		wantMethod string
+	}{
+		{name: "RSA", key: rsaKey, publicKey: &rsaKey.PublicKey, wantMethod: "RS256"},
+		{name: "EC", key: ecKey, publicKey: &ecKey.PublicKey, wantMethod: "ES384"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := NewClientAuthenticator(config.AuthConfig{
+				ClientID:         "events-api",
+				ClientAuthMethod: ClientAuthPrivateKeyJWT,
+				ClientKeyFile:    writeClientKey(t, tt.key),
+				ClientKeyID:      "events-api-key-1",
+			})
+			if err != nil {
+				t.Fatalf("NewClientAuthenticator() error = %v", err)
+			}
+
+			form := url.Values{}
+			header := make(http.Header)
+			if err := authenticator.Authenticate(form, header, testTokenURL); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if form.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+				t.Errorf("client_assertion_type = %q", form.Get("client_assertion_type"))
+			}
+			if form.Has("client_secret") || header.Get("Authorization") != "" {
+				t.Error("private_key_jwt must not send a client secret")
+			}
+
+			var claims jwt.RegisteredClaims
+			token, err := jwt.ParseWithClaims(form.Get("client_assertion"), &claims, func(token *jwt.Token) (any, error) {
+				return tt.publicKey, nil
+			}, jwt.WithAudience(testTokenURL), jwt.WithIssuer("events-api"), jwt.WithExpirationRequired())
+			if err != nil {
+				t.Fatalf("client assertion invalid: %v", err)
+			}
+			if token.Method.Alg() != tt.wantMethod {
+				t.Errorf("alg = %v, want %v", token.Method.Alg(), tt.wantMethod)
+			}
+			if token.Header["kid"] != "events-api-key-1" {
+				t.Errorf("kid = %v, want events-api-key-1", token.Header["kid"])
+			}
+			if claims.Subject != "events-api" || claims.ID == "" {
+				t.Errorf("sub = %q, jti = %q; want events-api and a jti", claims.Subject, claims.ID)
+			}
+		})
+	}
+}
+
+func TestNewClientAuthenticator_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		authConfig config.AuthConfig
+	}{
+		{name: "Unknown method", authConfig: config.AuthConfig{ClientAuthMethod: "tls_client_auth"}},
+		{name: "Missing key file", authConfig: config.AuthConfig{ClientAuthMethod: ClientAuthPrivateKeyJWT}},
+		{
+			name:       "Unreadable key file",
+			authConfig: config.AuthConfig{ClientAuthMethod: ClientAuthPrivateKeyJWT, ClientKeyFile: "/nonexistent/key.pem"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClientAuthenticator(tt.authConfig); err == nil {
+				t.Error("NewClientAuthenticator() expected error")
+			}
+		})
+	}
+}
+
+func TestIntrospectTokenAt_ClientSecretBasic(t *testing.T) {
+	var captured *http.Request
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			req.ParseForm()
+			return createMockResponse(http.StatusOK, `{"active":true,"sub":"user-123"}`), nil
+		},
+	}
+	authConfig := config.AuthConfig{ClientID: "events-api", ClientSecret: "secret", ClientAuthMethod: ClientAuthSecretBasic}
+
+	if _, err := IntrospectTokenAt(context.Background(), "http://keycloak:8080/introspect", "access-token", authConfig, client); err != nil {
+		t.Fatalf("IntrospectTokenAt(context.Background(), ) error = %v", err)
+	}
+	if user, password, ok := captured.BasicAuth(); !ok || user != "events-api" || password != "secret" {
+		t.Errorf("BasicAuth() = %q, %q, %v; want events-api, secret", user, password, ok)
+	}
+	if captured.PostForm.Has("client_secret") {
+		t.Error("client_secret must not be sent in the form")
+	}
+	if captured.PostForm.Get("token") != "access-token" {
+		t.Errorf("token = %q, want access-token", captured.PostForm.Get("token"))
+	}
+}
+
+func TestIntrospectionValidator_ReadsClientKeyOnce(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyFile := writeClientKey(t, key)
+	var assertion string
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			req.ParseForm()
+			assertion = req.PostForm.Get("client_assertion")
+			return createMockResponse(http.StatusOK, `{"active":true,"sub":"user-123"}`), nil
+		},
+	}
+	validator := mustIntrospectionValidator(t, config.AuthConfig{
+		KeycloakURL:      "http://keycloak:8080",
+		RealmName:        "events",
+		ClientID:         "events-api",
+		ClientAuthMethod: ClientAuthPrivateKeyJWT,
+		ClientKeyFile:    keyFile,
+	}, client)
+
+	// Requests are signed with the key loaded by the constructor
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("failed to remove key: %v", err)
+	}
+	if _, err := validator.ValidateToken(context.Background(), "access-token"); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if _, err := jwt.Parse(assertion, func(*jwt.Token) (any, error) { return key.Public(), nil }); err != nil {
+		t.Errorf("client assertion not signed by the client key: %v", err)
+	}
+
+	// A key file that cannot be read fails the constructors rather than each request
+	_, err := NewIntrospectionValidator(config.AuthConfig{ClientAuthMethod: ClientAuthPrivateKeyJWT, ClientKeyFile: keyFile}, client)
+	if err == nil {
+		t.Error("NewIntrospectionValidator() expected error for a missing key file")
+	}
+}
@@ -0,0 +1,250 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// defaultDiscoveryRefreshInterval is used when no refresh interval is configured
+const defaultDiscoveryRefreshInterval = time.Hour
+
+// discoveryRetryInterval delays the next fetch attempt after a failed one,
+// so an unreachable IdP is not asked again on every request
+const discoveryRetryInterval = 10 * time.Second
+
+// discoveryFetchTimeout bounds a single fetch of the discovery document
+const discoveryFetchTimeout = 10 * time.Second
+
+// ProviderMetadata represents the fields of an OpenID Provider configuration
+// document (/.well-known/openid-configuration) that the backend uses
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	IntrospectionEndpoint string `json:"introspection_endpoint"`
+
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// Endpoints holds the issuer and endpoint URLs of the identity provider
+type Endpoints struct {
+	Issuer           string
+	JWKSURL          string
+	TokenURL         string
+	IntrospectionURL string
+
+	SigningAlgorithms []string // advertised signing algorithms, empty if unknown
+}
+
+// EndpointResolver supplies the identity provider's endpoints to the validators
+// ctx bounds any request the resolver needs to make
+type EndpointResolver interface {
+	Endpoints(ctx context.Context) (Endpoints, error)
+}
+
+// KeycloakEndpoints returns the endpoints derived from KeycloakURL and RealmName
+// using Keycloak's URL layout
+func KeycloakEndpoints(authConfig config.AuthConfig) Endpoints {
+	realmURL := fmt.Sprintf("%s/realms/%s", authConfig.KeycloakURL, authConfig.RealmName)
+	return Endpoints{
+		Issuer:           realmURL,
+		JWKSURL:          realmURL + "/protocol/openid-connect/certs",
+		TokenURL:         realmURL + "/protocol/openid-connect/token",
+		IntrospectionURL: realmURL + "/protocol/openid-connect/token/introspect",
+	}
+}
+
+// NewEndpointResolver creates an EndpointResolver from AuthConfig
+// With DiscoveryURL set, endpoints are read from the discovery document;
+// otherwise they are derived from KeycloakURL and RealmName
+// ExpectedIssuer, if set, replaces the issuer in either case
+func NewEndpointResolver(authConfig config.AuthConfig, client HTTPClient) EndpointResolver {
+	var resolver EndpointResolver = staticEndpoints(KeycloakEndpoints(authConfig))
+	if authConfig.DiscoveryURL != "" {
+		resolver = NewDiscoveryClient(authConfig.DiscoveryURL, client, authConfig.DiscoveryRefreshInterval)
+	}
+	if authConfig.ExpectedIssuer != "" {
+		resolver = issuerOverride{resolver: resolver, issuer: authConfig.ExpectedIssuer}
+	}
+	return resolver
+}
+
+// staticEndpoints is an EndpointResolver for fixed endpoints
+type staticEndpoints Endpoints
+
+// Endpoints returns the fixed endpoints
+func (s staticEndpoints) Endpoints(ctx context.Context) (Endpoints, error) {
+	return Endpoints(s), nil
+}
+
+// issuerOverride replaces the issuer reported by another resolver, e.g. when the
+// backend reaches the IdP under a different hostname than the one in the tokens
+type issuerOverride struct {
+	resolver EndpointResolver
+	issuer   string
+}
+
+// Endpoints returns the wrapped resolver's endpoints with the overridden issuer
+func (o issuerOverride) Endpoints(ctx context.Context) (Endpoints, error) {
+	endpoints, err := o.resolver.Endpoints(ctx)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	endpoints.Issuer = o.issuer
+	return endpoints, nil
+}
+
+// FetchProviderMetadata reads the OpenID Provider configuration document
+// The request is bounded by ctx and by discoveryFetchTimeout
+func FetchProviderMetadata(ctx context.Context, discoveryURL string, client HTTPClient) (*ProviderMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("discovery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned status %d", resp.StatusCode)
+	}
+
+	var metadata ProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+	if metadata.Issuer == "" {
+		return nil, errors.New("discovery document has no issuer")
+	}
+
+	return &metadata, nil
+}
+
+// DiscoveryClient fetches the OpenID Provider configuration document and caches it
+// The document is fetched on first use and refreshed once the refresh interval has
+// elapsed; if a refresh fails, the previous document is kept in use
+// Only one fetch runs at a time and it runs without holding the lock, so callers
+// keep getting the cached document while a refresh is in flight
+type DiscoveryClient struct {
+	discoveryURL    string
+	client          HTTPClient
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu          sync.Mutex
+	metadata    *ProviderMetadata
+	lastErr     error
+	nextAttempt time.Time
+	fetching    chan struct{} // closed when the running fetch completes, nil if none runs
+}
+
+// NewDiscoveryClient creates a DiscoveryClient for the given discovery document URL
+func NewDiscoveryClient(discoveryURL string, client HTTPClient, refreshInterval time.Duration) *DiscoveryClient {
+	if client == nil {
+		client = defaultHTTPClient()
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultDiscoveryRefreshInterval
+	}
+	return &DiscoveryClient{
+		discoveryURL:    discoveryURL,
+		client:          client,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// Metadata returns the cached discovery document, fetching it if it is missing or due for refresh
+// Without a cached document, callers wait for a running fetch until ctx is done
+func (d *DiscoveryClient) Metadata(ctx context.Context) (*ProviderMetadata, error) {
+	for {
+		d.mu.Lock()
+		if d.now().Before(d.nextAttempt) {
+			metadata, err := d.metadata, d.lastErr
+			d.mu.Unlock()
+			if metadata == nil {
+				return nil, err
+			}
+			return metadata, nil
+		}
+		if d.fetching == nil {
+			d.fetching = make(chan struct{})
+			d.mu.Unlock()
+			return d.refresh(ctx)
+		}
+		metadata, fetching := d.metadata, d.fetching
+		d.mu.Unlock()
+
+		// Serve the stale document rather than waiting for the refresh
+		if metadata != nil {
+			return metadata, nil
+		}
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// refresh fetches the discovery document and records the outcome
+// The caller must have set d.fetching
+func (d *DiscoveryClient) refresh(ctx context.Context) (*ProviderMetadata, error) {
+	metadata, err := FetchProviderMetadata(ctx, d.discoveryURL, d.client)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	close(d.fetching)
+	d.fetching = nil
+
+	now := d.now()
+	if err != nil {
+		// A fetch cut short by the caller says nothing about the IdP, so the
+		// next caller may try again right away
+		if ctx.Err() == nil {
+			d.lastErr = err
+			d.nextAttempt = now.Add(discoveryRetryInterval)
+		}
+		if d.metadata == nil {
+			return nil, err
+		}
+		log.Printf("OIDC discovery refresh failed, using cached document: %v", err)
+		return d.metadata, nil
+	}
+
+	d.metadata = metadata
+	d.lastErr = nil
+	d.nextAttempt = now.Add(d.refreshInterval)
+	return d.metadata, nil
+}
+
+// Endpoints returns the endpoints listed in the discovery document
+func (d *DiscoveryClient) Endpoints(ctx context.Context) (Endpoints, error) {
+	metadata, err := d.Metadata(ctx)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	return Endpoints{
+		Issuer:           metadata.Issuer,
+		JWKSURL:          metadata.JWKSURI,
+		TokenURL:         metadata.TokenEndpoint,
+		IntrospectionURL: metadata.IntrospectionEndpoint,
+
+		SigningAlgorithms: metadata.IDTokenSigningAlgValuesSupported,
+	}, nil
+}
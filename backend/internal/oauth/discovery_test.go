@@ -0,0 +1,388 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// discoveryDocumentClient serves the given metadata for discovery requests and counts calls
+// It fails with a transport error while *fail is true
+func discoveryDocumentClient(calls *int, fail *bool, metadata ProviderMetadata) *MockHTTPClient {
+	body, _ := json.Marshal(metadata)
+	return countingClient(calls, func(req *http.Request) (*http.Response, error) {
+		if fail != nil && *fail {
+			return nil, errors.New("connection refused")
+		}
+		return createMockResponse(http.StatusOK, string(body)), nil
+	})
+}
+
+// testProviderMetadata returns a discovery document for a proxied Keycloak realm
+func testProviderMetadata() ProviderMetadata {
+	return ProviderMetadata{
+		Issuer:                "https://auth.example.com/realms/events",
+		JWKSURI:               "http://keycloak:8080/realms/events/protocol/openid-connect/certs",
+		TokenEndpoint:         "http://keycloak:8080/realms/events/protocol/openid-connect/token",
+		IntrospectionEndpoint: "http://keycloak:8080/realms/events/protocol/openid-connect/token/introspect",
+	}
+}
+
+func TestFetchProviderMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{
+			name:   "Valid document",
+			status: http.StatusOK,
+			body:   `{"issuer":"https://idp.example.com","jwks_uri":"https://idp.example.com/keys"}`,
+		},
+		{name: "Missing issuer", status: http.StatusOK, body: `{"jwks_uri":"https://idp.example.com/keys"}`, wantErr: true},
+		{name: "Not found", status: http.StatusNotFound, body: `{}`, wantErr: true},
+		{name: "Malformed body", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestedURL string
+			client := &MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					requestedURL = req.URL.String()
+					return createMockResponse(tt.status, tt.body), nil
+				},
+			}
+
+			discoveryURL := "https://idp.example.com/.well-known/openid-configuration"
+			metadata, err := FetchProviderMetadata(context.Background(), discoveryURL, client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchProviderMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requestedURL != discoveryURL {
+				t.Errorf("requested URL = %v, want %v", requestedURL, discoveryURL)
+			}
+			if !tt.wantErr && metadata.JWKSURI != "https://idp.example.com/keys" {
+				t.Errorf("JWKSURI = %v, want https://idp.example.com/keys", metadata.JWKSURI)
+			}
+		})
+	}
+}
+
+func TestDiscoveryClient_CachesAndRefreshes(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	discovery := NewDiscoveryClient("http://keycloak:8080/.well-known/openid-configuration",
+		discoveryDocumentClient(&calls, nil, testProviderMetadata()), time.Hour)
+	discovery.now = clock.now
+
+	for range 3 {
+		endpoints, err := discovery.Endpoints(context.Background())
+		if err != nil {
+			t.Fatalf("Endpoints() error = %v", err)
+		}
+		if endpoints.Issuer != "https://auth.example.com/realms/events" {
+			t.Errorf("Issuer = %v, want https://auth.example.com/realms/events", endpoints.Issuer)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("discovery calls = %d, want 1", calls)
+	}
+
+	clock.advance(time.Hour)
+	discovery.Endpoints(context.Background())
+	if calls != 2 {
+		t.Errorf("discovery calls after refresh interval = %d, want 2", calls)
+	}
+}
+
+func TestDiscoveryClient_KeepsDocumentWhenRefreshFails(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	fail := false
+	discovery := NewDiscoveryClient("http://keycloak:8080/.well-known/openid-configuration",
+		discoveryDocumentClient(&calls, &fail, testProviderMetadata()), time.Hour)
+	discovery.now = clock.now
+
+	if _, err := discovery.Endpoints(context.Background()); err != nil {
+		t.Fatalf("Endpoints() error = %v", err)
+	}
+
+	fail = true
+	clock.advance(time.Hour)
+	endpoints, err := discovery.Endpoints(context.Background())
+	if err != nil {
+		t.Fatalf("Endpoints() error = %v, want cached document", err)
+	}
+	if endpoints.JWKSURL != testProviderMetadata().JWKSURI {
+		t.Errorf("JWKSURL = %v, want %v", endpoints.JWKSURL, testProviderMetadata().JWKSURI)
+	}
+
+	// The failed refresh is not retried on every call
+	discovery.Endpoints(context.Background())
+	if calls != 2 {
+		t.Errorf("discovery calls = %d, want 2", calls)
+	}
+
+	fail = false
+	clock.advance(discoveryRetryInterval)
+	discovery.Endpoints(context.Background())
+	if calls != 3 {
+		t.Errorf("discovery calls after retry interval = %d, want 3", calls)
+	}
+}
+
+func TestDiscoveryClient_InitialFetchFails(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	fail := true
+	discovery := NewDiscoveryClient("http://keycloak:8080/.well-known/openid-configuration",
+		discoveryDocumentClient(&calls, &fail, testProviderMetadata()), time.Hour)
+	discovery.now = clock.now
+
+	for range 2 {
+		if _, err := discovery.Endpoints(context.Background()); err == nil {
+			t.Fatal("Endpoints() expected error")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("discovery calls = %d, want 1", calls)
+	}
+}
+
+func TestDiscoveryClient_ServesCachedDocumentDuringRefresh(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	discovery := NewDiscoveryClient("http://keycloak:8080/.well-known/openid-configuration",
+		discoveryDocumentClient(&calls, nil, testProviderMetadata()), time.Hour)
+	discovery.now = clock.now
+	if _, err := discovery.Endpoints(context.Background()); err != nil {
+		t.Fatalf("Endpoints() error = %v", err)
+	}
+
+	// Block the refresh until the other caller has been served
+	started := make(chan struct{})
+	release := make(chan struct{})
+	discovery.client = &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			close(started)
+			<-release
+			return discoveryDocumentClient(&calls, nil, testProviderMetadata()).Do(req)
+		},
+	}
+	clock.advance(time.Hour)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		discovery.Endpoints(context.Background())
+	}()
+	<-started
+
+	endpoints, err := discovery.Endpoints(context.Background())
+	if err != nil {
+		t.Fatalf("Endpoints() during refresh error = %v", err)
+	}
+	if endpoints.JWKSURL != testProviderMetadata().JWKSURI {
+		t.Errorf("JWKSURL = %v, want %v", endpoints.JWKSURL, testProviderMetadata().JWKSURI)
+	}
+	close(release)
+	<-done
+	if calls != 2 {
+		t.Errorf("discovery calls = %d, want 2", calls)
+	}
+}
+
+func TestDiscoveryClient_InitialFetchHonoursContext(t *testing.T) {
+	discovery := NewDiscoveryClient("http://keycloak:8080/.well-known/openid-configuration", &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := discovery.Endpoints(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Endpoints() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A fetch cut short by the caller does not delay the next attempt
+	calls := 0
+	discovery.client = discoveryDocumentClient(&calls, nil, testProviderMetadata())
+	if _, err := discovery.Endpoints(context.Background()); err != nil {
+		t.Fatalf("Endpoints() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("discovery calls = %d, want 1", calls)
+	}
+}
+
+func TestNewEndpointResolver(t *testing.T) {
+	tests := []struct {
+		name       string
+		authConfig config.AuthConfig
+		want       Endpoints
+	}{
+		{
+			name: "Derived from Keycloak URL",
+			authConfig: config.AuthConfig{
+				KeycloakURL: "http://keycloak:8080",
+				RealmName:   "events",
+			},
+			want: Endpoints{
+				Issuer:           "http://keycloak:8080/realms/events",
+				JWKSURL:          "http://keycloak:8080/realms/events/protocol/openid-connect/certs",
+				TokenURL:         "http://keycloak:8080/realms/events/protocol/openid-connect/token",
+				IntrospectionURL: "http://keycloak:8080/realms/events/protocol/openid-connect/token/introspect",
+			},
+		},
+		{
+			name: "Derived with issuer override",
+			authConfig: config.AuthConfig{
+				KeycloakURL:    "http://keycloak:8080",
+				RealmName:      "events",
+				ExpectedIssuer: "http://localhost:8081/realms/events",
+			},
+			want: Endpoints{
+				Issuer:           "http://localhost:8081/realms/events",
+				JWKSURL:          "http://keycloak:8080/realms/events/protocol/openid-connect/certs",
+				TokenURL:         "http://keycloak:8080/realms/events/protocol/openid-connect/token",
+				IntrospectionURL: "http://keycloak:8080/realms/events/protocol/openid-connect/token/introspect",
+			},
+		},
+		{
+			name: "Discovered",
+			authConfig: config.AuthConfig{
+				KeycloakURL:  "http://ignored:8080",
+				DiscoveryURL: "http://keycloak:8080/realms/events/.well-known/openid-configuration",
+			},
+			want: Endpoints{
+				Issuer:           testProviderMetadata().Issuer,
+				JWKSURL:          testProviderMetadata().JWKSURI,
+				TokenURL:         testProviderMetadata().TokenEndpoint,
+				IntrospectionURL: testProviderMetadata().IntrospectionEndpoint,
+			},
+		},
+		{
+			name: "Discovered with issuer override",
+			authConfig: config.AuthConfig{
+				DiscoveryURL:   "http://keycloak:8080/realms/events/.well-known/openid-configuration",
+				ExpectedIssuer: "http://localhost:8081/realms/events",
+			},
+			want: Endpoints{
+				Issuer:           "http://localhost:8081/realms/events",
+				JWKSURL:          testProviderMetadata().JWKSURI,
+				TokenURL:         testProviderMetadata().TokenEndpoint,
+				IntrospectionURL: testProviderMetadata().IntrospectionEndpoint,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			resolver := NewEndpointResolver(tt.authConfig, discoveryDocumentClient(&calls, nil, testProviderMetadata()))
+
+			got, err := resolver.Endpoints(context.Background())
+			if err != nil {
+				t.Fatalf("Endpoints() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Endpoints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntrospectionValidator_UsesDiscoveredEndpoint(t *testing.T) {
+	metadata := testProviderMetadata()
+	var introspectionURL string
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var body []byte
+			if req.Method == http.MethodGet {
+				body, _ = json.Marshal(metadata)
+			} else {
+				introspectionURL = req.URL.String()
+				body, _ = json.Marshal(TokenIntrospectionResponse{Active: true, Sub: "user-123"})
+			}
+			return createMockResponse(http.StatusOK, string(body)), nil
+		},
+	}
+
+	validator := mustIntrospectionValidator(t, config.AuthConfig{
+		DiscoveryURL: "http://keycloak:8080/realms/events/.well-known/openid-configuration",
+	}, client)
+
+	if _, err := validator.ValidateToken(context.Background(), "valid-token"); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if introspectionURL != metadata.IntrospectionEndpoint {
+		t.Errorf("introspection URL = %v, want %v", introspectionURL, metadata.IntrospectionEndpoint)
+	}
+}
+
+func TestNewJWKSValidatorFromConfig_Discovery(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ProviderMetadata{
+			Issuer:  "https://auth.example.com/realms/events",
+			JWKSURI: server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(keyPair.jwksResponse())
+	})
+
+	tests := []struct {
+		name           string
+		expectedIssuer string
+		wantIssuer     string
+	}{
+		{name: "Discovered issuer", wantIssuer: "https://auth.example.com/realms/events"},
+		{
+			name:           "Overridden issuer",
+			expectedIssuer: "http://localhost:8081/realms/events",
+			wantIssuer:     "http://localhost:8081/realms/events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := &MockHTTPClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					calls++
+					return server.Client().Do(req)
+				},
+			}
+			validator, err := NewJWKSValidatorFromConfig(context.Background(), config.AuthConfig{
+				DiscoveryURL:   server.URL + "/.well-known/openid-configuration",
+				ExpectedIssuer: tt.expectedIssuer,
+			}, client)
+			if err != nil {
+				t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+			}
+			if calls != 1 {
+				t.Errorf("discovery calls through the given client = %d, want 1", calls)
+			}
+			if validator.expectedIssuer != tt.wantIssuer {
+				t.Errorf("expectedIssuer = %v, want %v", validator.expectedIssuer, tt.wantIssuer)
+			}
+		})
+	}
+}
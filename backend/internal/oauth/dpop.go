@@ -0,0 +1,330 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// dpopProofType is the required typ header of a DPoP proof
+const dpopProofType = "dpop+jwt"
+
+// dpopClockSkew is how far in the future a proof's iat may lie
+const dpopClockSkew = 5 * time.Second
+
+// defaultDPoPProofMaxAge is used when no maximum proof age is configured
+const defaultDPoPProofMaxAge = time.Minute
+
+// dpopSigningMethods are the asymmetric algorithms accepted for DPoP proofs
+var dpopSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// ErrInvalidDPoPProof is returned when a DPoP proof fails validation
+var ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+
+// ErrDPoPNonceRequired is returned when a DPoP proof lacks the current server nonce;
+// the client must retry with the nonce from the DPoP-Nonce response header
+var ErrDPoPNonceRequired = errors.New("DPoP nonce required")
+
+// DPoPProof holds the validated claims of a DPoP proof
+type DPoPProof struct {
+	JKT      string // SHA-256 JWK thumbprint of the proof key
+	JTI      string
+	IssuedAt time.Time
+}
+
+// dpopProofClaims represents the claims we expect in a DPoP proof (internal use only)
+type dpopProofClaims struct {
+	jwt.RegisteredClaims
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	ATH   string `json:"ath"`
+	Nonce string `json:"nonce"`
+}
+
+// DPoPVerifier validates DPoP proofs (RFC 9449) and rejects replayed ones
+type DPoPVerifier struct {
+	maxAge time.Duration
+	now    func() time.Time
+	random io.Reader // source of nonces
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // jkt|jti -> replay entry expiry
+	lastPrune time.Time
+
+	requireNonce  bool
+	nonce         string
+	previousNonce string
+	nonceIssuedAt time.Time
+}
+
+// NewDPoPVerifier creates a DPoPVerifier
+// maxAge bounds how old a proof may be and how long its jti is remembered;
+// with requireNonce, proofs must carry a server nonce that rotates every maxAge
+func NewDPoPVerifier(maxAge time.Duration, requireNonce bool) *DPoPVerifier {
+	if maxAge <= 0 {
+		maxAge = defaultDPoPProofMaxAge
+	}
+	return &DPoPVerifier{
+		maxAge:       maxAge,
+		now:          time.Now,
+		random:       rand.Reader,
+		seen:         make(map[string]time.Time),
+		requireNonce: requireNonce,
+	}
+}
+
+// VerifyProof validates a DPoP proof for an HTTP request and, if accessToken is not
+// empty, that the proof was created for that token
+func (v *DPoPVerifier) VerifyProof(proof, method, requestURL, accessToken string) (*DPoPProof, error) {
+	var jkt string
+	token, err := jwt.ParseWithClaims(proof, &dpopProofClaims{}, func(token *jwt.Token) (any, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, fmt.Errorf("typ must be %s", dpopProofType)
+		}
+		jwk, ok := token.Header["jwk"].(map[string]any)
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+		key, thumbprint, err := parsePublicJWK(jwk)
+		if err != nil {
+			return nil, err
+		}
+		jkt = thumbprint
+		return key, nil
+	},
+		jwt.WithValidMethods(dpopSigningMethods),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+
+	claims, ok := token.Claims.(*dpopProofClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	// Check the request binding
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: missing jti", ErrInvalidDPoPProof)
+	}
+	if claims.HTM != method {
+		return nil, fmt.Errorf("%w: htm %q does not match %s", ErrInvalidDPoPProof, claims.HTM, method)
+	}
+	if !sameHTU(claims.HTU, requestURL) {
+		return nil, fmt.Errorf("%w: htu %q does not match %s", ErrInvalidDPoPProof, claims.HTU, requestURL)
+	}
+	if accessToken != "" && claims.ATH != accessTokenHash(accessToken) {
+		return nil, fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+
+	// Check the proof is fresh
+	now := v.now()
+	if claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: missing iat", ErrInvalidDPoPProof)
+	}
+	issuedAt := claims.IssuedAt.Time
+	if issuedAt.After(now.Add(dpopClockSkew)) || issuedAt.Before(now.Add(-v.maxAge)) {
+		return nil, fmt.Errorf("%w: iat outside the accepted window", ErrInvalidDPoPProof)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.requireNonce && !v.validNonceLocked(claims.Nonce, now) {
+		return nil, ErrDPoPNonceRequired
+	}
+
+	// Reject replays; entries expire once the proof would be too old anyway
+	key := jkt + "|" + claims.ID
+	if expiresAt, ok := v.seen[key]; ok && now.Before(expiresAt) {
+		return nil, fmt.Errorf("%w: jti already used", ErrInvalidDPoPProof)
+	}
+	v.pruneLocked(now)
+	v.seen[key] = issuedAt.Add(v.maxAge + dpopClockSkew)
+
+	return &DPoPProof{JKT: jkt, JTI: claims.ID, IssuedAt: issuedAt}, nil
+}
+
+// Nonce returns the current server nonce, or "" if nonces are not required
+func (v *DPoPVerifier) Nonce() string {
+	if !v.requireNonce {
+		return ""
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rotateNonceLocked(v.now())
+	return v.nonce
+}
+
+// validNonceLocked accepts the current and the previous nonce, so that clients
+// holding a nonce issued just before a rotation are not rejected
+func (v *DPoPVerifier) validNonceLocked(nonce string, now time.Time) bool {
+	v.rotateNonceLocked(now)
+	return nonce != "" && (nonce == v.nonce || nonce == v.previousNonce)
+}
+
+// rotateNonceLocked replaces the nonce once it is older than maxAge
+// If no random nonce can be generated, the current one stays in use
+func (v *DPoPVerifier) rotateNonceLocked(now time.Time) {
+	if v.nonce != "" && now.Sub(v.nonceIssuedAt) < v.maxAge {
+		return
+	}
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(v.random, buf); err != nil {
+		log.Printf("DPoP nonce rotation failed, keeping the current nonce: %v", err)
+		return
+	}
+	v.previousNonce = v.nonce
+	v.nonce = base64.RawURLEncoding.EncodeToString(buf)
+	v.nonceIssuedAt = now
+}
+
+// pruneLocked drops expired replay entries at most once per maxAge
+func (v *DPoPVerifier) pruneLocked(now time.Time) {
+	if now.Sub(v.lastPrune) < v.maxAge {
+		return
+	}
+	for key, expiresAt := range v.seen {
+		if !now.Before(expiresAt) {
+			delete(v.seen, key)
+		}
+	}
+	v.lastPrune = now
+}
+
+// accessTokenHash returns the ath value for an access token: base64url(SHA-256(token))
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// sameHTU compares a proof's htu with the request URL, ignoring query and fragment
+// and normalizing scheme and host case and default ports
+func sameHTU(htu, requestURL string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return normalizedHTU(a) == normalizedHTU(b)
+}
+
+// normalizedHTU returns scheme, host and path of a URL in normalized form
+func normalizedHTU(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return scheme + "://" + host + path
+}
+
+// parsePublicJWK converts a public JWK into a key usable by jwt and returns
+// its RFC 7638 SHA-256 thumbprint
+func parsePublicJWK(jwk map[string]any) (crypto.PublicKey, string, error) {
+	member := func(name string) string {
+		value, _ := jwk[name].(string)
+		return value
+	}
+	if member("d") != "" {
+		return nil, "", errors.New("jwk must not contain a private key")
+	}
+
+	// The thumbprint covers the required members only, in lexicographic order
+	var key crypto.PublicKey
+	var required map[string]string
+	switch kty := member("kty"); kty {
+	case "RSA":
+		n, err := decodeJWKInt(member("n"))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeJWKInt(member("e"))
+		if err != nil || !e.IsInt64() {
+			return nil, "", errors.New("invalid RSA exponent")
+		}
+		key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		required = map[string]string{"e": member("e"), "kty": kty, "n": member("n")}
+	case "EC":
+		var curve elliptic.Curve
+		switch member("crv") {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, "", fmt.Errorf("unsupported curve %q", member("crv"))
+		}
+		x, errX := decodeJWKInt(member("x"))
+		y, errY := decodeJWKInt(member("y"))
+		if errX != nil || errY != nil {
+			return nil, "", errors.New("invalid EC coordinates")
+		}
+		key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		required = map[string]string{"crv": member("crv"), "kty": kty, "x": member("x"), "y": member("y")}
+	case "OKP":
+		if member("crv") != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported curve %q", member("crv"))
+		}
+		x, err := base64.RawURLEncoding.DecodeString(member("x"))
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, "", errors.New("invalid Ed25519 key")
+		}
+		key = ed25519.PublicKey(x)
+		required = map[string]string{"crv": member("crv"), "kty": kty, "x": member("x")}
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q", kty)
+	}
+
+	// encoding/json sorts map keys, giving the canonical RFC 7638 form
+	canonical, err := json.Marshal(required)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return key, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// decodeJWKInt decodes a base64url-encoded unsigned big-endian integer
+func decodeJWKInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,258 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testDPoPURL = "https://api.example.com/events"
+
+// dpopTestKey is an EC key pair used to sign DPoP proofs in tests
+type dpopTestKey struct {
+	privateKey *ecdsa.PrivateKey
+	jwk        map[string]any
+}
+
+// generateDPoPTestKey creates a P-256 key and its public JWK
+func generateDPoPTestKey(t *testing.T) *dpopTestKey {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return &dpopTestKey{
+		privateKey: privateKey,
+		jwk: map[string]any{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(privateKey.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(privateKey.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the test key
+func (k *dpopTestKey) thumbprint(t *testing.T) string {
+	t.Helper()
+	_, jkt, err := parsePublicJWK(k.jwk)
+	if err != nil {
+		t.Fatalf("failed to compute thumbprint: %v", err)
+	}
+	return jkt
+}
+
+// proof creates a signed DPoP proof; modify can adjust header and claims before signing
+func (k *dpopTestKey) proof(t *testing.T, claims jwt.MapClaims, modify func(header map[string]any)) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = k.jwk
+	if modify != nil {
+		modify(token.Header)
+	}
+	signed, err := token.SignedString(k.privateKey)
+	if err != nil {
+		t.Fatalf("failed to sign proof: %v", err)
+	}
+	return signed
+}
+
+// validProofClaims returns DPoP proof claims for GET testDPoPURL with the given access token
+func validProofClaims(now time.Time, jti, accessToken string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"jti": jti,
+		"htm": "GET",
+		"htu": testDPoPURL,
+		"iat": now.Unix(),
+		"ath": accessTokenHash(accessToken),
+	}
+}
+
+func TestDPoPVerifier_VerifyProof(t *testing.T) {
+	key := generateDPoPTestKey(t)
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name         string
+		modifyClaims func(jwt.MapClaims)
+		modifyHeader func(map[string]any)
+		method       string
+		requestURL   string
+		wantErr      bool
+	}{
+		{name: "Valid"},
+		{name: "Query and default port ignored", requestURL: "https://API.example.com:443/events?page=2"},
+		{name: "Wrong method", method: "POST", wantErr: true},
+		{name: "Wrong URL", requestURL: "https://api.example.com/admin", wantErr: true},
+		{name: "Wrong access token hash", modifyClaims: func(c jwt.MapClaims) { c["ath"] = accessTokenHash("other") }, wantErr: true},
+		{name: "Missing jti", modifyClaims: func(c jwt.MapClaims) { delete(c, "jti") }, wantErr: true},
+		{name: "Missing iat", modifyClaims: func(c jwt.MapClaims) { delete(c, "iat") }, wantErr: true},
+		{name: "Too old", modifyClaims: func(c jwt.MapClaims) { c["iat"] = now.Add(-2 * time.Minute).Unix() }, wantErr: true},
+		{name: "In the future", modifyClaims: func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() }, wantErr: true},
+		{name: "Wrong typ", modifyHeader: func(h map[string]any) { h["typ"] = "JWT" }, wantErr: true},
+		{name: "Missing jwk", modifyHeader: func(h map[string]any) { delete(h, "jwk") }, wantErr: true},
+		{
+			name: "Private key in jwk",
+			modifyHeader: func(h map[string]any) {
+				jwk := map[string]any{"d": "secret"}
+				for k, v := range key.jwk {
+					jwk[k] = v
+				}
+				h["jwk"] = jwk
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewDPoPVerifier(time.Minute, false)
+			verifier.now = func() time.Time { return now }
+
+			claims := validProofClaims(now, "proof-1", "access-token")
+			if tt.modifyClaims != nil {
+				tt.modifyClaims(claims)
+			}
+			method := "GET"
+			if tt.method != "" {
+				method = tt.method
+			}
+			requestURL := testDPoPURL
+			if tt.requestURL != "" {
+				requestURL = tt.requestURL
+			}
+
+			proof, err := verifier.VerifyProof(key.proof(t, claims, tt.modifyHeader), method, requestURL, "access-token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyProof() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDPoPProof) {
+					t.Errorf("VerifyProof() error = %v, want ErrInvalidDPoPProof", err)
+				}
+				return
+			}
+			if proof.JKT != key.thumbprint(t) {
+				t.Errorf("JKT = %v, want %v", proof.JKT, key.thumbprint(t))
+			}
+		})
+	}
+}
+
+func TestDPoPVerifier_RejectsReplay(t *testing.T) {
+	key := generateDPoPTestKey(t)
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	verifier := NewDPoPVerifier(time.Minute, false)
+	verifier.now = clock.now
+
+	proof := key.proof(t, validProofClaims(clock.current, "proof-1", "access-token"), nil)
+
+	if _, err := verifier.VerifyProof(proof, "GET", testDPoPURL, "access-token"); err != nil {
+		t.Fatalf("VerifyProof() first use error = %v", err)
+	}
+	clock.advance(10 * time.Second)
+	if _, err := verifier.VerifyProof(proof, "GET", testDPoPURL, "access-token"); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Errorf("VerifyProof() replay error = %v, want ErrInvalidDPoPProof", err)
+	}
+
+	// A fresh jti from the same key is accepted
+	fresh := key.proof(t, validProofClaims(clock.current, "proof-2", "access-token"), nil)
+	if _, err := verifier.VerifyProof(fresh, "GET", testDPoPURL, "access-token"); err != nil {
+		t.Errorf("VerifyProof() fresh proof error = %v", err)
+	}
+}
+
+func TestDPoPVerifier_Nonce(t *testing.T) {
+	key := generateDPoPTestKey(t)
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	verifier := NewDPoPVerifier(time.Minute, true)
+	verifier.now = clock.now
+
+	withoutNonce := key.proof(t, validProofClaims(clock.current, "proof-1", "access-token"), nil)
+	if _, err := verifier.VerifyProof(withoutNonce, "GET", testDPoPURL, "access-token"); !errors.Is(err, ErrDPoPNonceRequired) {
+		t.Fatalf("VerifyProof() without nonce error = %v, want ErrDPoPNonceRequired", err)
+	}
+
+	nonce := verifier.Nonce()
+	if nonce == "" {
+		t.Fatal("Nonce() returned empty nonce")
+	}
+	claims := validProofClaims(clock.current, "proof-2", "access-token")
+	claims["nonce"] = nonce
+	if _, err := verifier.VerifyProof(key.proof(t, claims, nil), "GET", testDPoPURL, "access-token"); err != nil {
+		t.Fatalf("VerifyProof() with nonce error = %v", err)
+	}
+
+	// After one rotation the previous nonce is still accepted, after two it is not
+	clock.advance(time.Minute)
+	claims = validProofClaims(clock.current, "proof-3", "access-token")
+	claims["nonce"] = nonce
+	if _, err := verifier.VerifyProof(key.proof(t, claims, nil), "GET", testDPoPURL, "access-token"); err != nil {
+		t.Errorf("VerifyProof() with previous nonce error = %v", err)
+	}
+	clock.advance(time.Minute)
+	claims = validProofClaims(clock.current, "proof-4", "access-token")
+	claims["nonce"] = nonce
+	if _, err := verifier.VerifyProof(key.proof(t, claims, nil), "GET", testDPoPURL, "access-token"); !errors.Is(err, ErrDPoPNonceRequired) {
+		t.Errorf("VerifyProof() with stale nonce error = %v, want ErrDPoPNonceRequired", err)
+	}
+}
+
+func TestDPoPVerifier_NonceRandomFailure(t *testing.T) {
+	key := generateDPoPTestKey(t)
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	verifier := NewDPoPVerifier(time.Minute, true)
+	verifier.now = clock.now
+
+	nonce := verifier.Nonce()
+	verifier.random = iotest.ErrReader(errors.New("entropy unavailable"))
+	clock.advance(2 * time.Minute)
+
+	if got := verifier.Nonce(); got != nonce {
+		t.Errorf("Nonce() = %q, want previous nonce %q", got, nonce)
+	}
+	claims := validProofClaims(clock.current, "proof-1", "access-token")
+	claims["nonce"] = nonce
+	if _, err := verifier.VerifyProof(key.proof(t, claims, nil), "GET", testDPoPURL, "access-token"); err != nil {
+		t.Errorf("VerifyProof() with kept nonce error = %v", err)
+	}
+
+	// Without any nonce issued yet, proofs cannot be accepted
+	fresh := NewDPoPVerifier(time.Minute, true)
+	fresh.random = iotest.ErrReader(errors.New("entropy unavailable"))
+	if got := fresh.Nonce(); got != "" {
+		t.Errorf("Nonce() = %q, want empty nonce", got)
+	}
+	claims = validProofClaims(time.Now(), "proof-2", "access-token")
+	claims["nonce"] = ""
+	if _, err := fresh.VerifyProof(key.proof(t, claims, nil), "GET", testDPoPURL, "access-token"); !errors.Is(err, ErrDPoPNonceRequired) {
+		t.Errorf("VerifyProof() error = %v, want ErrDPoPNonceRequired", err)
+	}
+}
+
+func TestParsePublicJWK_RFC7638Thumbprint(t *testing.T) {
+	// Example from RFC 7638, section 3.1
+	jwk := map[string]any{
+		"kty": "RSA",
+		"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	}
+
+	_, jkt, err := parsePublicJWK(jwk)
+	if err != nil {
+		t.Fatalf("parsePublicJWK() error = %v", err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; jkt != want {
+		t.Errorf("thumbprint = %v, want %v", jkt, want)
+	}
+}
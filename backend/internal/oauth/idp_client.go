@@ -0,0 +1,218 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// defaultIdPTimeout bounds requests to the identity provider when no timeout is configured
+const defaultIdPTimeout = 5 * time.Second
+
+// ErrIdPUnavailable is returned when the identity provider cannot be reached, keeps
+// answering with server errors, or the circuit breaker is open
+// Callers should report it as a temporary failure (503) rather than an invalid token (401)
+var ErrIdPUnavailable = errors.New("identity provider unavailable")
+
+// defaultHTTPClient returns the client used when none is passed to a constructor
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: defaultIdPTimeout}
+}
+
+// NewIdPClient creates the HTTP client for requests to the identity provider:
+// requests time out after IdPTimeout, 5xx responses and transport errors are retried
+// up to IdPMaxRetries times, and a circuit breaker fails fast once
+// IdPCircuitBreakerThreshold requests in a row have failed
+func NewIdPClient(authConfig config.AuthConfig) *ResilientClient {
+	timeout := authConfig.IdPTimeout
+	if timeout <= 0 {
+		timeout = defaultIdPTimeout
+	}
+	return NewResilientClient(&http.Client{Timeout: timeout},
+		authConfig.IdPMaxRetries,
+		authConfig.IdPRetryBackoff,
+		NewCircuitBreaker(authConfig.IdPCircuitBreakerThreshold, authConfig.IdPCircuitBreakerCooldown),
+	)
+}
+
+// ResilientClient decorates an HTTPClient with bounded retries and a circuit breaker
+// It is safe for concurrent use
+type ResilientClient struct {
+	next       HTTPClient
+	maxRetries int
+	backoff    time.Duration
+	breaker    *CircuitBreaker // nil disables the circuit breaker
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// NewResilientClient creates a ResilientClient
+// Retry n waits backoff * 2^(n-1); a nil breaker disables fail-fast behavior
+func NewResilientClient(next HTTPClient, maxRetries int, backoff time.Duration, breaker *CircuitBreaker) *ResilientClient {
+	return &ResilientClient{
+		next:       next,
+		maxRetries: max(maxRetries, 0),
+		backoff:    backoff,
+		breaker:    breaker,
+		sleep:      sleepContext,
+	}
+}
+
+// Do sends the request, retrying transport errors and 5xx responses
+// Once all attempts have failed, the error wraps ErrIdPUnavailable
+func (c *ResilientClient) Do(req *http.Request) (*http.Response, error) {
+	if c.breaker != nil && !c.breaker.allow() {
+		return nil, fmt.Errorf("%w: circuit breaker open", ErrIdPUnavailable)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if req.Body != nil && req.GetBody == nil {
+				break // the body cannot be replayed
+			}
+			if err := c.sleep(req.Context(), c.backoff<<(attempt-1)); err != nil {
+				c.abandon()
+				return nil, err
+			}
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			c.abandon()
+			return nil, err
+		}
+		resp, err := c.next.Do(attemptReq)
+		if err != nil {
+			// The caller gave up; that says nothing about the identity provider
+			if ctxErr := req.Context().Err(); ctxErr != nil {
+				c.abandon()
+				return nil, ctxErr
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			continue
+		}
+
+		c.record(true)
+		return resp, nil
+	}
+
+	c.record(false)
+	return nil, fmt.Errorf("%w: %w", ErrIdPUnavailable, lastErr)
+}
+
+// record reports the outcome of a request to the circuit breaker
+func (c *ResilientClient) record(success bool) {
+	if c.breaker != nil {
+		c.breaker.record(success)
+	}
+}
+
+// abandon tells the circuit breaker that a request ended without an outcome
+func (c *ResilientClient) abandon() {
+	if c.breaker != nil {
+		c.breaker.abandon()
+	}
+}
+
+// rewind returns the request for the given attempt with a fresh body
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return retry, nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CircuitBreaker opens after a number of consecutive failures and rejects requests
+// until the cooldown has passed; then a single trial request decides whether it
+// closes again or stays open for another cooldown
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu         sync.Mutex
+	failures   int
+	openedAt   time.Time
+	open       bool
+	trialTaken bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker, or returns nil if threshold is not positive
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown || b.trialTaken {
+		return false
+	}
+	b.trialTaken = true
+	return true
+}
+
+// abandon releases the trial slot of a request that was canceled by its caller
+func (b *CircuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialTaken = false
+}
+
+// record updates the breaker with the outcome of an allowed request
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		b.open = false
+		b.trialTaken = false
+		return
+	}
+
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+		b.trialTaken = false
+	}
+}
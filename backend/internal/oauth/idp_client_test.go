@@ -0,0 +1,168 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusSequenceClient answers with the given statuses in order, repeating the last one
+// A status of 0 is answered with a transport error
+func statusSequenceClient(calls *int, statuses ...int) *MockHTTPClient {
+	return countingClient(calls, func(req *http.Request) (*http.Response, error) {
+		status := statuses[min(*calls-1, len(statuses)-1)]
+		if req.Body != nil {
+			if body, _ := io.ReadAll(req.Body); string(body) != "token=abc" {
+				return nil, errors.New("request body not replayed")
+			}
+		}
+		if status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return createMockResponse(status, `{}`), nil
+	})
+}
+
+// newTestResilientClient creates a ResilientClient that does not sleep between retries
+func newTestResilientClient(next HTTPClient, maxRetries int, breaker *CircuitBreaker) (*ResilientClient, *[]time.Duration) {
+	var waits []time.Duration
+	client := NewResilientClient(next, maxRetries, 100*time.Millisecond, breaker)
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return client, &waits
+}
+
+func newTestRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://keycloak:8080/introspect", strings.NewReader("token=abc"))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	return req
+}
+
+func TestResilientClient_Retries(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int
+		wantCalls   int
+		wantStatus  int
+		wantIdPDown bool
+	}{
+		{name: "Success", statuses: []int{200}, wantCalls: 1, wantStatus: 200},
+		{name: "Recovers after 5xx", statuses: []int{503, 502, 200}, wantCalls: 3, wantStatus: 200},
+		{name: "Recovers after transport error", statuses: []int{0, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "Client errors are not retried", statuses: []int{401}, wantCalls: 1, wantStatus: 401},
+		{name: "Retries exhausted", statuses: []int{500}, wantCalls: 3, wantIdPDown: true},
+		{name: "Unreachable", statuses: []int{0}, wantCalls: 3, wantIdPDown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client, waits := newTestResilientClient(statusSequenceClient(&calls, tt.statuses...), 2, nil)
+
+			resp, err := client.Do(newTestRequest(t))
+			if tt.wantIdPDown {
+				if !errors.Is(err, ErrIdPUnavailable) {
+					t.Errorf("Do() error = %v, want %v", err, ErrIdPUnavailable)
+				}
+			} else if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			} else if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			for i, wait := range *waits {
+				if want := 100 * time.Millisecond << i; wait != want {
+					t.Errorf("wait %d = %v, want %v", i, wait, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResilientClient_CircuitBreaker(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	breaker := NewCircuitBreaker(2, 30*time.Second)
+	breaker.now = clock.now
+
+	calls := 0
+	statuses := []int{0, 0, 0, 200}
+	client, _ := newTestResilientClient(statusSequenceClient(&calls, statuses...), 0, breaker)
+
+	// Two failures open the breaker
+	for range 2 {
+		client.Do(newTestRequest(t))
+	}
+
+	// While open, requests fail fast without reaching the identity provider
+	if _, err := client.Do(newTestRequest(t)); !errors.Is(err, ErrIdPUnavailable) {
+		t.Errorf("Do() error = %v, want %v", err, ErrIdPUnavailable)
+	}
+	if calls != 2 {
+		t.Errorf("calls while open = %d, want 2", calls)
+	}
+
+	// After the cooldown, a failed trial keeps it open for another cooldown
+	clock.advance(30 * time.Second)
+	client.Do(newTestRequest(t))
+	client.Do(newTestRequest(t))
+	if calls != 3 {
+		t.Errorf("calls after failed trial = %d, want 3", calls)
+	}
+
+	// A successful trial closes it
+	clock.advance(30 * time.Second)
+	if _, err := client.Do(newTestRequest(t)); err != nil {
+		t.Fatalf("Do() trial error = %v", err)
+	}
+	if _, err := client.Do(newTestRequest(t)); err != nil {
+		t.Errorf("Do() after close error = %v", err)
+	}
+	if calls != 5 {
+		t.Errorf("calls after close = %d, want 5", calls)
+	}
+}
+
+func TestResilientClient_CanceledRequest(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	client, _ := newTestResilientClient(statusSequenceClient(&calls, 0), 2, breaker)
+	req := newTestRequest(t).WithContext(ctx)
+
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want %v", err, context.Canceled)
+	}
+	// A caller giving up is not counted as an identity provider failure
+	if !breaker.allow() {
+		t.Error("circuit breaker opened by a canceled request")
+	}
+}
+
+func TestIntrospectionValidator_IdPUnavailable(t *testing.T) {
+	calls := 0
+	client, _ := newTestResilientClient(countingClient(&calls, func(req *http.Request) (*http.Response, error) {
+		return createMockResponse(http.StatusServiceUnavailable, ""), nil
+	}), 1, nil)
+	validator := mustIntrospectionValidator(t, umaTestConfig(), client)
+
+	_, err := validator.ValidateToken(context.Background(), "valid-token")
+	if !errors.Is(err, ErrIdPUnavailable) {
+		t.Errorf("ValidateToken() error = %v, want %v", err, ErrIdPUnavailable)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
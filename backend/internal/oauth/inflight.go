@@ -0,0 +1,65 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errInflightAborted is returned to waiting callers if the leading call panicked
+var errInflightAborted = errors.New("token validation aborted")
+
+// inflightCall is a validation in progress that other callers can wait on
+type inflightCall struct {
+	done   chan struct{}
+	claims *AuthClaims
+	err    error
+}
+
+// inflightGroup deduplicates concurrent validations of the same token
+// so that N simultaneous callers trigger a single upstream request
+type inflightGroup struct {
+	mu        sync.Mutex
+	calls     map[string]*inflightCall
+	coalesced atomic.Uint64
+}
+
+// do runs fn for key unless a call for the same key is already in flight,
+// in which case it waits for that call and returns its result
+// A waiting caller gives up when its ctx is done; waiting callers receive their own deep copy of the claims
+func (g *inflightGroup) do(ctx context.Context, key string, fn func() (*AuthClaims, error)) (*AuthClaims, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		g.coalesced.Add(1)
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return call.claims.clone(), call.err
+	}
+
+	call := &inflightCall{done: make(chan struct{}), err: errInflightAborted}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.claims, call.err = fn()
+	return call.claims.clone(), call.err
+}
+
+// coalescedCount returns how many callers were served by another caller's request
+func (g *inflightGroup) coalescedCount() uint64 {
+	return g.coalesced.Load()
+}
@@ -1,8 +1,11 @@
 package oauth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,23 +18,45 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// ErrTokenInactive is returned when the introspection endpoint reports the token as inactive
+var ErrTokenInactive = errors.New("token is not active")
+
 // IntrospectToken validates the token against Keycloak's introspection endpoint
 // and returns the extracted claims
-func IntrospectToken(token string, authConfig config.AuthConfig, client HTTPClient) (*AuthClaims, error) {
-	introspectionURL := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token/introspect",
-		authConfig.KeycloakURL, authConfig.RealmName)
+// The endpoint is derived from KeycloakURL and RealmName; use IntrospectionValidator
+// to resolve it via OIDC discovery instead
+func IntrospectToken(ctx context.Context, token string, authConfig config.AuthConfig, client HTTPClient) (*AuthClaims, error) {
+	return IntrospectTokenAt(ctx, KeycloakEndpoints(authConfig).IntrospectionURL, token, authConfig, client)
+}
+
+// IntrospectTokenAt validates the token against the given introspection endpoint
+// and returns the extracted claims
+// Besides active, the exp, nbf and iat members are checked with AuthConfig.ClockSkewLeeway
+// and AuthConfig.MaxTokenAge; a rejection for one of them yields a *TemporalClaimError
+// The claim mapping and client authenticator are created on every call;
+// IntrospectionValidator creates them once
+func IntrospectTokenAt(ctx context.Context, introspectionURL, token string, authConfig config.AuthConfig, client HTTPClient) (*AuthClaims, error) {
+	mapping, err := NewClaimMapping(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := NewClientAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return introspectTokenAt(ctx, introspectionURL, token, authConfig, client, mapping, authenticator)
+}
 
+// introspectTokenAt is IntrospectTokenAt with a compiled claim mapping and client authenticator
+func introspectTokenAt(ctx context.Context, introspectionURL, token string, authConfig config.AuthConfig, client HTTPClient, mapping ClaimMapping, authenticator ClientAuthenticator) (*AuthClaims, error) {
 	// Prepare the introspection request
 	data := url.Values{}
 	data.Set("token", token)
-	data.Set("client_id", authConfig.ClientID)
-	data.Set("client_secret", authConfig.ClientSecret)
 
-	req, err := http.NewRequest(http.MethodPost, introspectionURL, strings.NewReader(data.Encode()))
+	req, err := newClientAuthRequest(ctx, introspectionURL, data, authenticator)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create introspection request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Calling the introspection endpoint
 	resp, err := client.Do(req)
@@ -45,15 +70,27 @@ func IntrospectToken(token string, authConfig config.AuthConfig, client HTTPClie
 		return nil, fmt.Errorf("introspection returned status %d", resp.StatusCode)
 	}
 
-	// Extract and parse the response-body into TokenIntrospectionResponse
+	// Extract and parse the response-body into TokenIntrospectionResponse, and into a
+	// generic object for the claim mapping
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection response: %w", err)
+	}
 	var introspectionResp TokenIntrospectionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&introspectionResp); err != nil {
+	if err := json.Unmarshal(body, &introspectionResp); err != nil {
 		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
 	}
 
 	// Check if the token is active, if not, return error
 	if !introspectionResp.Active {
-		return nil, fmt.Errorf("token is not active")
+		return nil, ErrTokenInactive
+	}
+
+	// Do not rely on active alone: the identity provider's clock may differ from ours
+	policy := temporalPolicy{leeway: authConfig.ClockSkewLeeway, maxAge: authConfig.MaxTokenAge}
+	exp, nbf, iat := unixTime(introspectionResp.Exp), unixTime(introspectionResp.Nbf), unixTime(introspectionResp.Iat)
+	if err := policy.check(exp, nbf, iat); err != nil {
+		return nil, err
 	}
 
 	// Parse scopes from space-separated string
@@ -62,13 +99,26 @@ func IntrospectToken(token string, authConfig config.AuthConfig, client HTTPClie
 		scopes = strings.Split(introspectionResp.Scope, " ")
 	}
 
+	payload, err := decodeClaims(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+
 	claims := &AuthClaims{
-		Subject:  introspectionResp.Sub,
-		Username: introspectionResp.Username,
-		Scopes:   scopes,
-		// Roles would typically come from realm_access.roles or resource_access in JWT
-		// For introspection, we'd need additional claims in the response
+		Scopes: scopes,
+		Issuer: introspectionResp.Iss,
+		Realm:  RealmFromIssuer(introspectionResp.Iss),
+
+		TokenID:   introspectionResp.Jti,
+		SessionID: introspectionResp.Sid,
+
+		Confirmation: introspectionResp.Cnf,
+		Actor:        introspectionResp.Act,
+
+		IssuedAt:  iat,
+		ExpiresAt: exp,
 	}
+	mapping.apply(payload, claims)
 
 	return claims, nil
 }
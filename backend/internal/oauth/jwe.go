@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// ErrTokenDecryption is returned when an encrypted (JWE) token cannot be decrypted
+var ErrTokenDecryption = errors.New("token decryption failed")
+
+// jweKeyAlgorithms are the accepted JWE key management algorithms
+var jweKeyAlgorithms = []jose.KeyAlgorithm{
+	jose.RSA_OAEP, jose.RSA_OAEP_256,
+	jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW,
+}
+
+// jweContentEncryption are the accepted JWE content encryption algorithms
+var jweContentEncryption = []jose.ContentEncryption{jose.A256GCM}
+
+// IsEncryptedToken reports whether the token is in JWE compact serialization
+// (five parts) rather than JWS compact serialization (three parts)
+func IsEncryptedToken(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// TokenDecrypter decrypts nested JWT access tokens, i.e. a signed JWT wrapped in a JWE
+// The key is selected by the JWE kid header; without kid, a set with a single key is used
+type TokenDecrypter struct {
+	keys jose.JSONWebKeySet
+}
+
+// NewTokenDecrypter creates a TokenDecrypter from a set of private keys
+func NewTokenDecrypter(keys jose.JSONWebKeySet) (*TokenDecrypter, error) {
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("no token decryption keys")
+	}
+	for _, key := range keys.Keys {
+		if key.IsPublic() || !key.Valid() {
+			return nil, fmt.Errorf("token decryption key %q is not a valid private key", key.KeyID)
+		}
+	}
+	return &TokenDecrypter{keys: keys}, nil
+}
+
+// LoadTokenDecrypter creates a TokenDecrypter from a JWK Set file of private keys
+func LoadTokenDecrypter(path string) (*TokenDecrypter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token decryption keys: %w", err)
+	}
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse token decryption keys: %w", err)
+	}
+	return NewTokenDecrypter(keys)
+}
+
+// Decrypt returns the signed JWT contained in the JWE token
+// The JWT still has to be verified
+func (d *TokenDecrypter) Decrypt(token string) (string, error) {
+	encrypted, err := jose.ParseEncryptedCompact(token, jweKeyAlgorithms, jweContentEncryption)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTokenDecryption, err)
+	}
+
+	key, err := d.key(encrypted.Header.KeyID)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := encrypted.Decrypt(key)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTokenDecryption, err)
+	}
+
+	// Only nested JWTs are accepted; an encrypted but unsigned token is not trustworthy
+	nested := string(plaintext)
+	if strings.Count(nested, ".") != 2 {
+		return "", fmt.Errorf("%w: payload is not a signed JWT", ErrTokenDecryption)
+	}
+	return nested, nil
+}
+
+// key selects the decryption key for the given kid
+func (d *TokenDecrypter) key(kid string) (jose.JSONWebKey, error) {
+	if kid == "" {
+		if len(d.keys.Keys) == 1 {
+			return d.keys.Keys[0], nil
+		}
+		return jose.JSONWebKey{}, fmt.Errorf("%w: token has no kid", ErrTokenDecryption)
+	}
+	keys := d.keys.Key(kid)
+	if len(keys) == 0 {
+		return jose.JSONWebKey{}, fmt.Errorf("%w: unknown kid %q", ErrTokenDecryption, kid)
+	}
+	return keys[0], nil
+}
@@ -0,0 +1,229 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+// testDecryptionKeys holds an RSA and an EC private key for token decryption
+type testDecryptionKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+// generateDecryptionKeys creates decryption keys with kids "rsa-enc" and "ec-enc"
+func generateDecryptionKeys(t *testing.T) (*testDecryptionKeys, jose.JSONWebKeySet) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return &testDecryptionKeys{rsa: rsaKey, ec: ecKey}, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: rsaKey, KeyID: "rsa-enc", Use: "enc"},
+		{Key: ecKey, KeyID: "ec-enc", Use: "enc"},
+	}}
+}
+
+// encryptToken wraps a signed JWT in a compact JWE for the given recipient key
+func encryptToken(t *testing.T, jws string, alg jose.KeyAlgorithm, enc jose.ContentEncryption, key any, kid string) string {
+	t.Helper()
+	encrypter, err := jose.NewEncrypter(enc, jose.Recipient{Algorithm: alg, Key: key, KeyID: kid},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		t.Fatalf("failed to create encrypter: %v", err)
+	}
+	encrypted, err := encrypter.Encrypt([]byte(jws))
+	if err != nil {
+		t.Fatalf("failed to encrypt token: %v", err)
+	}
+	compact, err := encrypted.CompactSerialize()
+	if err != nil {
+		t.Fatalf("failed to serialize token: %v", err)
+	}
+	return compact
+}
+
+func TestTokenDecrypter_Decrypt(t *testing.T) {
+	keys, keySet := generateDecryptionKeys(t)
+	decrypter, err := NewTokenDecrypter(keySet)
+	if err != nil {
+		t.Fatalf("NewTokenDecrypter() error = %v", err)
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	const jws = "header.payload.signature"
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "RSA-OAEP", token: encryptToken(t, jws, jose.RSA_OAEP, jose.A256GCM, &keys.rsa.PublicKey, "rsa-enc")},
+		{name: "RSA-OAEP-256", token: encryptToken(t, jws, jose.RSA_OAEP_256, jose.A256GCM, &keys.rsa.PublicKey, "rsa-enc")},
+		{name: "ECDH-ES", token: encryptToken(t, jws, jose.ECDH_ES, jose.A256GCM, &keys.ec.PublicKey, "ec-enc")},
+		{name: "ECDH-ES+A256KW", token: encryptToken(t, jws, jose.ECDH_ES_A256KW, jose.A256GCM, &keys.ec.PublicKey, "ec-enc")},
+		{name: "Unknown kid", token: encryptToken(t, jws, jose.RSA_OAEP, jose.A256GCM, &keys.rsa.PublicKey, "other"), wantErr: true},
+		{name: "No kid with several keys", token: encryptToken(t, jws, jose.RSA_OAEP, jose.A256GCM, &keys.rsa.PublicKey, ""), wantErr: true},
+		{name: "Encrypted for another key", token: encryptToken(t, jws, jose.RSA_OAEP, jose.A256GCM, &otherKey.PublicKey, "rsa-enc"), wantErr: true},
+		{name: "Unsupported content encryption", token: encryptToken(t, jws, jose.RSA_OAEP, jose.A128GCM, &keys.rsa.PublicKey, "rsa-enc"), wantErr: true},
+		{name: "Payload is not a signed JWT", token: encryptToken(t, "plain", jose.RSA_OAEP, jose.A256GCM, &keys.rsa.PublicKey, "rsa-enc"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decrypter.Decrypt(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTokenDecryption) {
+					t.Errorf("Decrypt() error = %v, want ErrTokenDecryption", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != jws {
+				t.Errorf("Decrypt() = %q, want %q", got, jws)
+			}
+		})
+	}
+}
+
+func TestTokenDecrypter_SingleKeyWithoutKid(t *testing.T) {
+	keys, keySet := generateDecryptionKeys(t)
+	decrypter, err := NewTokenDecrypter(jose.JSONWebKeySet{Keys: keySet.Keys[:1]})
+	if err != nil {
+		t.Fatalf("NewTokenDecrypter() error = %v", err)
+	}
+
+	token := encryptToken(t, "header.payload.signature", jose.RSA_OAEP, jose.A256GCM, &keys.rsa.PublicKey, "")
+	if _, err := decrypter.Decrypt(token); err != nil {
+		t.Errorf("Decrypt() error = %v", err)
+	}
+}
+
+func TestLoadTokenDecrypter(t *testing.T) {
+	keys, keySet := generateDecryptionKeys(t)
+	dir := t.TempDir()
+
+	privateFile := filepath.Join(dir, "private.json")
+	data, _ := json.Marshal(keySet)
+	os.WriteFile(privateFile, data, 0o600)
+
+	publicFile := filepath.Join(dir, "public.json")
+	data, _ = json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &keys.rsa.PublicKey, KeyID: "rsa-enc"}}})
+	os.WriteFile(publicFile, data, 0o600)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "Private keys", path: privateFile},
+		{name: "Public key", path: publicFile, wantErr: true},
+		{name: "Missing file", path: filepath.Join(dir, "missing.json"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTokenDecrypter(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadTokenDecrypter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_EncryptedToken(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	keys, keySet := generateDecryptionKeys(t)
+
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	data, _ := json.Marshal(keySet)
+	os.WriteFile(keysFile, data, 0o600)
+
+	authConfig := realm.AuthConfig()
+	authConfig.TokenDecryptionKeysFile = keysFile
+	validator, err := NewJWKSValidatorFromConfig(context.Background(), authConfig, nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+	}
+	plainValidator, err := NewJWKSValidatorFromConfig(context.Background(), realm.AuthConfig(), nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+	}
+
+	signed := realm.MintToken(oauthtest.TokenOptions{Subject: "user-123"})
+	encrypted := encryptToken(t, signed, jose.RSA_OAEP_256, jose.A256GCM, &keys.rsa.PublicKey, "rsa-enc")
+	forged := encryptToken(t, signed[:len(signed)-4]+"AAAA", jose.RSA_OAEP_256, jose.A256GCM, &keys.rsa.PublicKey, "rsa-enc")
+
+	tests := []struct {
+		name          string
+		validator     *JWKSValidator
+		token         string
+		wantErr       bool
+		decryptionErr bool
+	}{
+		{name: "Encrypted token", validator: validator, token: encrypted},
+		{name: "Signed token", validator: validator, token: signed},
+		{name: "Encrypted token with invalid signature", validator: validator, token: forged, wantErr: true},
+		{name: "Encrypted token without decryption keys", validator: plainValidator, token: encrypted, wantErr: true, decryptionErr: true},
+		{name: "Signed token without decryption keys", validator: plainValidator, token: signed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.validator.ValidateToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.decryptionErr && !errors.Is(err, ErrTokenDecryption) {
+				t.Errorf("ValidateToken() error = %v, want ErrTokenDecryption", err)
+			}
+			if !tt.wantErr && claims.Subject != "user-123" {
+				t.Errorf("Subject = %q, want user-123", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestMultiIssuerValidator_EncryptedToken(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	keys, keySet := generateDecryptionKeys(t)
+	decrypter, err := NewTokenDecrypter(keySet)
+	if err != nil {
+		t.Fatalf("NewTokenDecrypter() error = %v", err)
+	}
+
+	stub := &stubValidator{claims: &AuthClaims{Subject: "user-123"}}
+	validator := NewMultiIssuerValidator(map[string]TokenValidator{realm.Issuer(): stub})
+	token := encryptToken(t, realm.MintToken(oauthtest.TokenOptions{}), jose.ECDH_ES, jose.A256GCM, &keys.ec.PublicKey, "ec-enc")
+
+	if _, err := validator.ValidateToken(context.Background(), token); !errors.Is(err, ErrTokenDecryption) {
+		t.Errorf("ValidateToken() without decrypter error = %v, want ErrTokenDecryption", err)
+	}
+
+	validator.decrypter = decrypter
+	claims, err := validator.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Issuer != realm.Issuer() || stub.calls != 1 {
+		t.Errorf("Issuer = %q, calls = %d, want %q, 1", claims.Issuer, stub.calls, realm.Issuer())
+	}
+}
@@ -2,8 +2,11 @@ package oauth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/MicahParks/keyfunc/v3"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,41 +16,162 @@ import (
 // jwtClaims represents the claims we expect in the JWT (internal use only)
 type jwtClaims struct {
 	jwt.RegisteredClaims
-	Scope    string   `json:"scope"`
-	ClientID string   `json:"client_id"`
-	Azp      string   `json:"azp"`
-	Aud      []string `json:"aud"`
+	Scope    string           `json:"scope"`
+	ClientID string           `json:"client_id"`
+	Azp      string           `json:"azp"`
+	Aud      jwt.ClaimStrings `json:"aud"` // Keycloak emits a string or an array
+	Sid      string           `json:"sid"`
+	Cnf      Confirmation     `json:"cnf"`
+	Act      *Actor           `json:"act"`
 }
 
+// ErrInvalidAudience is returned when none of the token's aud values is an expected audience
+var ErrInvalidAudience = errors.New("token audience not accepted")
+
+// ErrInvalidAuthorizedParty is returned when the token's azp is not in the allow-list
+var ErrInvalidAuthorizedParty = errors.New("token authorized party not accepted")
+
 // JWKSValidator wraps keyfunc for JWKS-based JWT validation
 type JWKSValidator struct {
 	keyfunc        keyfunc.Keyfunc
 	expectedIssuer string
+
+	expectedAudiences        []string        // empty disables the audience check
+	allowedAuthorizedParties []string        // empty disables the azp check
+	algorithms               []string        // accepted JWS algorithms
+	decrypter                *TokenDecrypter // nil rejects encrypted tokens
+	temporal                 temporalPolicy
+	mapping                  ClaimMapping
+}
+
+// JWKSOption configures optional checks of a JWKSValidator
+type JWKSOption func(*JWKSValidator)
+
+// WithExpectedAudiences requires the token's aud claim to contain at least one of the given audiences
+func WithExpectedAudiences(audiences ...string) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.expectedAudiences = audiences
+	}
+}
+
+// WithAllowedAuthorizedParties requires the token's azp claim to be one of the given client IDs
+func WithAllowedAuthorizedParties(clientIDs ...string) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.allowedAuthorizedParties = clientIDs
+	}
+}
+
+// WithLeeway tolerates the given clock skew when checking exp, nbf and iat
+func WithLeeway(leeway time.Duration) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.temporal.leeway = leeway
+	}
+}
+
+// WithMaxTokenAge rejects tokens whose iat lies further in the past than maxAge
+func WithMaxTokenAge(maxAge time.Duration) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.temporal.maxAge = maxAge
+	}
+}
+
+// WithAllowedAlgorithms sets the JWS algorithms tokens may be signed with
+// Without this option, RS256, RS384 and RS512 are accepted
+func WithAllowedAlgorithms(algorithms ...string) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.algorithms = algorithms
+	}
+}
+
+// WithDecrypter accepts encrypted (JWE) tokens, decrypting them with the given keys
+// before the signature of the nested JWT is verified
+func WithDecrypter(decrypter *TokenDecrypter) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.decrypter = decrypter
+	}
+}
+
+// WithClaimMapping reads the identity fields of AuthClaims as declared by mapping
+// instead of DefaultClaimMapping
+func WithClaimMapping(mapping ClaimMapping) JWKSOption {
+	return func(v *JWKSValidator) {
+		v.mapping = mapping
+	}
 }
 
 // NewJWKSValidator creates a new validator with automatic JWKS caching
-func NewJWKSValidator(ctx context.Context, jwksURL, expectedIssuer string) (*JWKSValidator, error) {
+// It fails if an allowed algorithm is not supported, see IsSupportedAlgorithm
+func NewJWKSValidator(ctx context.Context, jwksURL, expectedIssuer string, opts ...JWKSOption) (*JWKSValidator, error) {
+	v := &JWKSValidator{expectedIssuer: expectedIssuer, algorithms: defaultAlgorithms, mapping: DefaultClaimMapping()}
+	for _, opt := range opts {
+		opt(v)
+	}
+	for _, alg := range v.algorithms {
+		if !IsSupportedAlgorithm(alg) {
+			return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+	}
+
 	kf, err := keyfunc.NewDefaultCtx(ctx, []string{jwksURL})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JWKS keyfunc: %w", err)
 	}
-	return &JWKSValidator{keyfunc: kf, expectedIssuer: expectedIssuer}, nil
+	v.keyfunc = kf
+	return v, nil
 }
 
 // NewJWKSValidatorFromConfig creates a JWKSValidator from AuthConfig
-func NewJWKSValidatorFromConfig(ctx context.Context, authConfig config.AuthConfig) (*JWKSValidator, error) {
-	jwksURL := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/certs",
-		authConfig.KeycloakURL, authConfig.RealmName)
-	expectedIssuer := fmt.Sprintf("%s/realms/%s",
-		authConfig.KeycloakURL, authConfig.RealmName)
-	return NewJWKSValidator(ctx, jwksURL, expectedIssuer)
+// The JWKS URL and expected issuer are resolved once via NewEndpointResolver using
+// client, or the default client if nil; the key set itself is refreshed by keyfunc
+// The allowed algorithms are AuthConfig.AllowedAlgorithms or, if unset, the supported
+// ones among the discovery document's id_token_signing_alg_values_supported
+func NewJWKSValidatorFromConfig(ctx context.Context, authConfig config.AuthConfig, client HTTPClient) (*JWKSValidator, error) {
+	endpoints, err := NewEndpointResolver(authConfig, client).Endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve JWKS endpoint: %w", err)
+	}
+	if endpoints.JWKSURL == "" {
+		return nil, errors.New("identity provider has no jwks_uri")
+	}
+	algorithms, err := resolveAlgorithms(authConfig.AllowedAlgorithms, endpoints.SigningAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := NewClaimMapping(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	var decrypter *TokenDecrypter
+	if authConfig.TokenDecryptionKeysFile != "" {
+		if decrypter, err = LoadTokenDecrypter(authConfig.TokenDecryptionKeysFile); err != nil {
+			return nil, err
+		}
+	}
+	return NewJWKSValidator(ctx, endpoints.JWKSURL, endpoints.Issuer,
+		WithAllowedAlgorithms(algorithms...),
+		WithDecrypter(decrypter),
+		WithClaimMapping(mapping),
+		WithExpectedAudiences(authConfig.ExpectedAudiences...),
+		WithAllowedAuthorizedParties(authConfig.AllowedAuthorizedParties...),
+		WithLeeway(authConfig.ClockSkewLeeway),
+		WithMaxTokenAge(authConfig.MaxTokenAge),
+	)
 }
 
 // ValidateToken validates a JWT and returns AuthClaims
-func (v *JWKSValidator) ValidateToken(tokenString string) (*AuthClaims, error) {
+// Validation is local, so ctx is not used
+// A token rejected for its exp, nbf or iat claim yields a *TemporalClaimError
+// Encrypted tokens are decrypted first if the validator has a TokenDecrypter
+func (v *JWKSValidator) ValidateToken(ctx context.Context, tokenString string) (*AuthClaims, error) {
+	tokenString, err := v.decrypt(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Time-based claims are checked below, with the same policy as introspection results
 	token, err := jwt.ParseWithClaims(tokenString, &jwtClaims{}, v.keyfunc.Keyfunc,
-		jwt.WithIssuer(v.expectedIssuer),
-		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithValidMethods(v.algorithms),
+		jwt.WithoutClaimsValidation(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("token validation failed: %w", err)
@@ -57,15 +181,72 @@ func (v *JWKSValidator) ValidateToken(tokenString string) (*AuthClaims, error) {
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	if claims.Issuer != v.expectedIssuer {
+		return nil, fmt.Errorf("token validation failed: %w", jwt.ErrTokenInvalidIssuer)
+	}
+	if err := v.temporal.check(numericDate(claims.ExpiresAt), numericDate(claims.NotBefore), numericDate(claims.IssuedAt)); err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+
+	// Check audience and authorized party
+	if len(v.expectedAudiences) > 0 && !slices.ContainsFunc(claims.Aud, func(aud string) bool {
+		return slices.Contains(v.expectedAudiences, aud)
+	}) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudience, []string(claims.Aud))
+	}
+	if len(v.allowedAuthorizedParties) > 0 && !slices.Contains(v.allowedAuthorizedParties, claims.Azp) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAuthorizedParty, claims.Azp)
+	}
+
+	// Convert to AuthClaims; the identity fields are read from the raw payload as mapped
+	payload, err := decodeJWTPayload(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
 
-	// Convert to AuthClaims
 	var scopes []string
 	if claims.Scope != "" {
 		scopes = strings.Split(claims.Scope, " ")
 	}
 
-	return &AuthClaims{
-		Subject: claims.Subject,
-		Scopes:  scopes,
-	}, nil
+	authClaims := &AuthClaims{
+		Scopes: scopes,
+		Issuer: claims.Issuer,
+		Realm:  RealmFromIssuer(claims.Issuer),
+
+		TokenID:   claims.ID,
+		SessionID: claims.Sid,
+
+		Confirmation: claims.Cnf,
+		Actor:        claims.Act,
+	}
+	v.mapping.apply(payload, authClaims)
+	authClaims.IssuedAt = numericDate(claims.IssuedAt)
+	authClaims.ExpiresAt = numericDate(claims.ExpiresAt)
+
+	return authClaims, nil
+}
+
+// decodeJWTPayload returns the claims of a compact JWT as a generic JSON object
+func decodeJWTPayload(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, jwt.ErrTokenMalformed
+	}
+	data, err := jwt.NewParser().DecodeSegment(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return decodeClaims(data)
+}
+
+// decrypt returns the nested JWT of an encrypted token, or a signed token unchanged
+func (v *JWKSValidator) decrypt(token string) (string, error) {
+	if !IsEncryptedToken(token) {
+		return token, nil
+	}
+	if v.decrypter == nil {
+		return "", fmt.Errorf("%w: no decryption keys configured", ErrTokenDecryption)
+	}
+	return v.decrypter.Decrypt(token)
 }
@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -116,7 +117,7 @@ func TestNewJWKSValidator_InvalidURL_ValidateTokenFails(t *testing.T) {
 	}
 
 	// But token validation should fail because no keys are available
-	_, err = validator.ValidateToken("any.token.here")
+	_, err = validator.ValidateToken(context.Background(), "any.token.here")
 	if err == nil {
 		t.Fatal("ValidateToken() expected error when no JWKS keys available, got nil")
 	}
@@ -140,7 +141,7 @@ func TestNewJWKSValidatorFromConfig_URLConstruction(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	validator, err := NewJWKSValidatorFromConfig(ctx, authConfig)
+	validator, err := NewJWKSValidatorFromConfig(ctx, authConfig, nil)
 
 	if err != nil {
 		t.Fatalf("NewJWKSValidatorFromConfig() error = %v, want nil", err)
@@ -180,7 +181,7 @@ func TestJWKSValidator_ValidToken(t *testing.T) {
 
 	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
 
-	authClaims, err := validator.ValidateToken(token)
+	authClaims, err := validator.ValidateToken(context.Background(), token)
 
 	if err != nil {
 		t.Fatalf("ValidateToken() error = %v, want nil", err)
@@ -197,6 +198,9 @@ func TestJWKSValidator_ValidToken(t *testing.T) {
 	if authClaims.Scopes[0] != "events:read" {
 		t.Errorf("Scopes[0] = %v, want %v", authClaims.Scopes[0], "events:read")
 	}
+	if authClaims.Issuer != server.URL {
+		t.Errorf("Issuer = %v, want %v", authClaims.Issuer, server.URL)
+	}
 	if authClaims.Scopes[1] != "events:write" {
 		t.Errorf("Scopes[1] = %v, want %v", authClaims.Scopes[1], "events:write")
 	}
@@ -224,7 +228,7 @@ func TestJWKSValidator_InvalidSignature(t *testing.T) {
 	// Sign with wrong key
 	token := wrongKeyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
 
-	_, err = validator.ValidateToken(token)
+	_, err = validator.ValidateToken(context.Background(), token)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for invalid signature, got nil")
@@ -251,7 +255,7 @@ func TestJWKSValidator_ExpiredToken(t *testing.T) {
 
 	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
 
-	_, err = validator.ValidateToken(token)
+	_, err = validator.ValidateToken(context.Background(), token)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for expired token, got nil")
@@ -278,7 +282,7 @@ func TestJWKSValidator_WrongIssuer(t *testing.T) {
 
 	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
 
-	_, err = validator.ValidateToken(token)
+	_, err = validator.ValidateToken(context.Background(), token)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for wrong issuer, got nil")
@@ -310,7 +314,7 @@ func TestJWKSValidator_UnsupportedAlgorithm(t *testing.T) {
 		t.Fatalf("failed to sign token: %v", err)
 	}
 
-	_, err = validator.ValidateToken(tokenString)
+	_, err = validator.ValidateToken(context.Background(), tokenString)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for unsupported algorithm, got nil")
@@ -340,7 +344,7 @@ func TestJWKSValidator_MalformedToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := validator.ValidateToken(tt.token)
+			_, err := validator.ValidateToken(context.Background(), tt.token)
 
 			if err == nil {
 				t.Errorf("ValidateToken(%q) expected error, got nil", tt.token)
@@ -394,7 +398,7 @@ func TestJWKSValidator_ScopeParsing(t *testing.T) {
 
 			token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
 
-			authClaims, err := validator.ValidateToken(token)
+			authClaims, err := validator.ValidateToken(context.Background(), token)
 
 			if err != nil {
 				t.Fatalf("ValidateToken() error = %v, want nil", err)
@@ -455,7 +459,7 @@ func TestJWKSValidator_RS384Algorithm(t *testing.T) {
 
 	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS384)
 
-	authClaims, err := validator.ValidateToken(token)
+	authClaims, err := validator.ValidateToken(context.Background(), token)
 
 	if err != nil {
 		t.Fatalf("ValidateToken() with RS384 error = %v, want nil", err)
@@ -506,7 +510,7 @@ func TestJWKSValidator_RS512Algorithm(t *testing.T) {
 
 	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS512)
 
-	authClaims, err := validator.ValidateToken(token)
+	authClaims, err := validator.ValidateToken(context.Background(), token)
 
 	if err != nil {
 		t.Fatalf("ValidateToken() with RS512 error = %v, want nil", err)
@@ -539,7 +543,7 @@ func TestJWKSValidator_AlgNoneAttack(t *testing.T) {
 	// Token with empty signature (alg:none tokens have no signature)
 	tokenString := header + "." + payload + "."
 
-	_, err = validator.ValidateToken(tokenString)
+	_, err = validator.ValidateToken(context.Background(), tokenString)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for 'alg: none' attack token, got nil")
@@ -585,3 +589,182 @@ func TestJwtClaims_StructTags(t *testing.T) {
 		t.Error("expected 'aud' field in JSON")
 	}
 }
+
+func TestJWKSValidator_KeycloakRolesAndProfile(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	ctx := context.Background()
+	validator, err := NewJWKSValidator(ctx, server.URL, server.URL)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	claims := jwt.MapClaims{
+		"sub":                "user-123",
+		"iss":                server.URL,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"preferred_username": "jdoe",
+		"email":              "jdoe@example.com",
+		"realm_access": map[string]any{
+			"roles": []string{"offline_access", "org-maintainer"},
+		},
+		"resource_access": map[string]any{
+			"events-api": map[string]any{"roles": []string{"org-maintainer"}},
+			"account":    map[string]any{"roles": []string{"view-profile"}},
+		},
+	}
+
+	token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
+
+	authClaims, err := validator.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v, want nil", err)
+	}
+
+	if authClaims.Username != "jdoe" {
+		t.Errorf("Username = %v, want %v", authClaims.Username, "jdoe")
+	}
+	if authClaims.Email != "jdoe@example.com" {
+		t.Errorf("Email = %v, want %v", authClaims.Email, "jdoe@example.com")
+	}
+
+	wantRoles := []string{"offline_access", "org-maintainer", "account:view-profile", "events-api:org-maintainer"}
+	if len(authClaims.Roles) != len(wantRoles) {
+		t.Fatalf("Roles = %v, want %v", authClaims.Roles, wantRoles)
+	}
+	for i, role := range wantRoles {
+		if authClaims.Roles[i] != role {
+			t.Errorf("Roles[%d] = %v, want %v", i, authClaims.Roles[i], role)
+		}
+	}
+
+	if !authClaims.HasRole("events-api:org-maintainer") {
+		t.Error("expected namespaced client role 'events-api:org-maintainer'")
+	}
+	if authClaims.HasRole("view-profile") {
+		t.Error("client roles must not be exposed without their client prefix")
+	}
+}
+
+func TestJWKSValidator_AudienceValidation(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	ctx := context.Background()
+	validator, err := NewJWKSValidator(ctx, server.URL, server.URL,
+		WithExpectedAudiences("events-api"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		aud     any
+		wantErr error
+	}{
+		{"single string audience", "events-api", nil},
+		{"audience array containing expected", []string{"account", "events-api"}, nil},
+		{"other audience", "account", ErrInvalidAudience},
+		{"missing audience", nil, ErrInvalidAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{
+				"sub": "user-123",
+				"iss": server.URL,
+				"exp": time.Now().Add(time.Hour).Unix(),
+				"iat": time.Now().Unix(),
+			}
+			if tt.aud != nil {
+				claims["aud"] = tt.aud
+			}
+			token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
+
+			_, err := validator.ValidateToken(context.Background(), token)
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("ValidateToken() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_AuthorizedPartyValidation(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	ctx := context.Background()
+	validator, err := NewJWKSValidator(ctx, server.URL, server.URL,
+		WithAllowedAuthorizedParties("events-frontend"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		azp     string
+		wantErr error
+	}{
+		{"allowed client", "events-frontend", nil},
+		{"other client", "admin-cli", ErrInvalidAuthorizedParty},
+		{"missing azp", "", ErrInvalidAuthorizedParty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{
+				"sub": "user-123",
+				"iss": server.URL,
+				"exp": time.Now().Add(time.Hour).Unix(),
+				"iat": time.Now().Unix(),
+			}
+			if tt.azp != "" {
+				claims["azp"] = tt.azp
+			}
+			token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
+
+			_, err := validator.ValidateToken(context.Background(), token)
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("ValidateToken() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewJWKSValidatorFromConfig_AudienceOptions(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	validator, err := NewJWKSValidatorFromConfig(context.Background(), config.AuthConfig{
+		KeycloakURL:              server.URL,
+		RealmName:                "test-realm",
+		ExpectedAudiences:        []string{"events-api"},
+		AllowedAuthorizedParties: []string{"events-frontend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v, want nil", err)
+	}
+
+	if len(validator.expectedAudiences) != 1 || validator.expectedAudiences[0] != "events-api" {
+		t.Errorf("expectedAudiences = %v, want [events-api]", validator.expectedAudiences)
+	}
+	if len(validator.allowedAuthorizedParties) != 1 || validator.allowedAuthorizedParties[0] != "events-frontend" {
+		t.Errorf("allowedAuthorizedParties = %v, want [events-frontend]", validator.allowedAuthorizedParties)
+	}
+}
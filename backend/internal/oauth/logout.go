@@ -0,0 +1,137 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// BackchannelLogoutEvent is the member of a logout token's events claim that marks it
+// as an OIDC Back-Channel Logout token
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// ErrInvalidLogoutToken is returned when a logout token fails validation
+var ErrInvalidLogoutToken = errors.New("invalid logout token")
+
+// LogoutToken holds the claims of a validated logout token
+type LogoutToken struct {
+	Issuer    string
+	Subject   string // sub claim - empty if only the session is identified
+	SessionID string // sid claim - empty if all sessions of the subject are logged out
+	IssuedAt  time.Time
+}
+
+// logoutTokenClaims represents the claims we expect in a logout token (internal use only)
+type logoutTokenClaims struct {
+	jwt.RegisteredClaims
+	Sid    string                     `json:"sid"`
+	Events map[string]json.RawMessage `json:"events"`
+	Nonce  string                     `json:"nonce"`
+}
+
+// LogoutTokenValidator validates OIDC Back-Channel Logout tokens using the keys
+// and issuer of a JWKSValidator
+type LogoutTokenValidator struct {
+	jwks      *JWKSValidator
+	audiences []string
+}
+
+// NewLogoutTokenValidator creates a LogoutTokenValidator
+// audiences are the client IDs Keycloak sends logout tokens for; the token's aud
+// must contain at least one of them
+func NewLogoutTokenValidator(jwks *JWKSValidator, audiences ...string) *LogoutTokenValidator {
+	return &LogoutTokenValidator{jwks: jwks, audiences: audiences}
+}
+
+// ValidateLogoutToken validates a logout token as defined by OIDC Back-Channel Logout 1.0
+func (v *LogoutTokenValidator) ValidateLogoutToken(tokenString string) (*LogoutToken, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &logoutTokenClaims{}, v.jwks.keyfunc.Keyfunc,
+		jwt.WithIssuer(v.jwks.expectedIssuer),
+		jwt.WithValidMethods(v.jwks.algorithms),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.jwks.temporal.leeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLogoutToken, err)
+	}
+
+	claims, ok := token.Claims.(*logoutTokenClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidLogoutToken
+	}
+
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(v.audiences, aud)
+	}) {
+		return nil, fmt.Errorf("%w: audience %v not accepted", ErrInvalidLogoutToken, []string(claims.Audience))
+	}
+	if _, ok := claims.Events[BackchannelLogoutEvent]; !ok {
+		return nil, fmt.Errorf("%w: missing back-channel logout event", ErrInvalidLogoutToken)
+	}
+	if claims.Sid == "" && claims.Subject == "" {
+		return nil, fmt.Errorf("%w: neither sid nor sub present", ErrInvalidLogoutToken)
+	}
+	if claims.Nonce != "" {
+		return nil, fmt.Errorf("%w: nonce must not be present", ErrInvalidLogoutToken)
+	}
+	if claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: missing iat", ErrInvalidLogoutToken)
+	}
+
+	return &LogoutToken{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		SessionID: claims.Sid,
+		IssuedAt:  claims.IssuedAt.Time,
+	}, nil
+}
+
+// MultiIssuerLogoutValidator routes each logout token to the LogoutTokenValidator of its issuer
+// As with MultiIssuerValidator, the unverified iss claim only picks the validator
+type MultiIssuerLogoutValidator struct {
+	validators map[string]*LogoutTokenValidator // issuer -> validator
+}
+
+// NewMultiIssuerLogoutValidator creates a MultiIssuerLogoutValidator from validators keyed by issuer
+func NewMultiIssuerLogoutValidator(validators map[string]*LogoutTokenValidator) *MultiIssuerLogoutValidator {
+	return &MultiIssuerLogoutValidator{validators: validators}
+}
+
+// NewMultiIssuerLogoutValidatorFromConfig creates a LogoutTokenValidator per entry of
+// AuthConfig.TrustedIssuers, accepting AuthConfig.BackchannelLogoutAudiences
+// Logout tokens are always verified locally, whatever validation method an entry names
+func NewMultiIssuerLogoutValidatorFromConfig(ctx context.Context, authConfig config.AuthConfig, client HTTPClient) (*MultiIssuerLogoutValidator, error) {
+	validators := make(map[string]*LogoutTokenValidator, len(authConfig.TrustedIssuers))
+	for _, entry := range authConfig.TrustedIssuers {
+		issuer, _ := ParseTrustedIssuer(entry, ValidationMethodJWKS)
+		if _, exists := validators[issuer]; exists {
+			return nil, fmt.Errorf("duplicate trusted issuer: %s", issuer)
+		}
+
+		jwks, err := NewJWKSValidatorFromConfig(ctx, issuerAuthConfig(authConfig, issuer), client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logout token validator for issuer %s: %w", issuer, err)
+		}
+		validators[issuer] = NewLogoutTokenValidator(jwks, authConfig.BackchannelLogoutAudiences...)
+	}
+	return NewMultiIssuerLogoutValidator(validators), nil
+}
+
+// ValidateLogoutToken validates the logout token with the validator of its issuer
+func (v *MultiIssuerLogoutValidator) ValidateLogoutToken(tokenString string) (*LogoutToken, error) {
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &unverified); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLogoutToken, err)
+	}
+	validator, ok := v.validators[unverified.Issuer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %w: %q", ErrInvalidLogoutToken, ErrUntrustedIssuer, unverified.Issuer)
+	}
+	return validator.ValidateLogoutToken(tokenString)
+}
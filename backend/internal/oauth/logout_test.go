@@ -0,0 +1,242 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+func TestLogoutTokenValidator_ValidateLogoutToken(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	jwksValidator, err := NewJWKSValidator(context.Background(), server.URL, server.URL)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	validator := NewLogoutTokenValidator(jwksValidator, "events-frontend")
+
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    server.URL,
+			"aud":    "events-frontend",
+			"sub":    "user-123",
+			"sid":    "session-1",
+			"iat":    now.Unix(),
+			"exp":    now.Add(time.Minute).Unix(),
+			"jti":    "logout-1",
+			"events": map[string]any{BackchannelLogoutEvent: map[string]any{}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "Valid", modify: func(c jwt.MapClaims) {}},
+		{name: "Only sub", modify: func(c jwt.MapClaims) { delete(c, "sid") }},
+		{name: "Wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "Wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "events-api" }, wantErr: true},
+		{name: "Missing event", modify: func(c jwt.MapClaims) { c["events"] = map[string]any{} }, wantErr: true},
+		{name: "Missing sid and sub", modify: func(c jwt.MapClaims) { delete(c, "sid"); delete(c, "sub") }, wantErr: true},
+		{name: "Nonce present", modify: func(c jwt.MapClaims) { c["nonce"] = "abc" }, wantErr: true},
+		{name: "Missing iat", modify: func(c jwt.MapClaims) { delete(c, "iat") }, wantErr: true},
+		{name: "Expired", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+			token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
+
+			logout, err := validator.ValidateLogoutToken(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateLogoutToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLogoutToken) {
+					t.Errorf("ValidateLogoutToken() error = %v, want ErrInvalidLogoutToken", err)
+				}
+				return
+			}
+			if logout.Subject != "user-123" {
+				t.Errorf("Subject = %v, want user-123", logout.Subject)
+			}
+			if logout.IssuedAt.Unix() != now.Unix() {
+				t.Errorf("IssuedAt = %v, want %v", logout.IssuedAt, now)
+			}
+		})
+	}
+}
+
+func TestMultiIssuerLogoutValidator(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+
+	// Both realms are served by the same Keycloak, reached via KeycloakURL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(keyPair.jwksResponse())
+	}))
+	defer server.Close()
+
+	validator, err := NewMultiIssuerLogoutValidatorFromConfig(context.Background(), config.AuthConfig{
+		KeycloakURL:                server.URL,
+		TrustedIssuers:             []string{testIssuerEvents + "|introspection", testIssuerSports},
+		BackchannelLogoutAudiences: []string{"events-frontend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMultiIssuerLogoutValidatorFromConfig() error = %v", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		issuer  string
+		wantErr bool
+	}{
+		{name: "Primary realm", issuer: testIssuerEvents},
+		{name: "Other trusted realm", issuer: testIssuerSports},
+		{name: "Untrusted issuer", issuer: "http://localhost:8081/realms/evil", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := keyPair.createTestToken(t, jwt.MapClaims{
+				"iss":    tt.issuer,
+				"aud":    "events-frontend",
+				"sid":    "session-1",
+				"iat":    now.Unix(),
+				"exp":    now.Add(time.Minute).Unix(),
+				"events": map[string]any{BackchannelLogoutEvent: map[string]any{}},
+			}, jwt.SigningMethodRS256)
+
+			logout, err := validator.ValidateLogoutToken(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateLogoutToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLogoutToken) {
+					t.Errorf("ValidateLogoutToken() error = %v, want ErrInvalidLogoutToken", err)
+				}
+				return
+			}
+			if logout.Issuer != tt.issuer {
+				t.Errorf("Issuer = %v, want %v", logout.Issuer, tt.issuer)
+			}
+		})
+	}
+}
+
+func TestSessionRevocations_Session(t *testing.T) {
+	loggedOutAt := time.Unix(1_700_000_000, 0)
+	revocations := NewSessionRevocations(time.Hour)
+	revocations.now = func() time.Time { return loggedOutAt }
+	revocations.Revoke(&LogoutToken{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt})
+
+	tests := []struct {
+		name   string
+		claims *AuthClaims
+		want   bool
+	}{
+		{
+			name:   "Token of logged out session",
+			claims: &AuthClaims{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt.Add(-time.Minute)},
+			want:   true,
+		},
+		{
+			name:   "Token issued at logout time",
+			claims: &AuthClaims{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt},
+			want:   true,
+		},
+		{
+			name:   "Token without iat",
+			claims: &AuthClaims{Subject: "user-123", SessionID: "session-1"},
+			want:   true,
+		},
+		{
+			name:   "Other session of the same subject",
+			claims: &AuthClaims{Subject: "user-123", SessionID: "session-2", IssuedAt: loggedOutAt.Add(-time.Minute)},
+			want:   false,
+		},
+		{
+			name:   "Token issued after logout",
+			claims: &AuthClaims{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt.Add(time.Second)},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revocations.IsRevoked(tt.claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionRevocations_Subject(t *testing.T) {
+	loggedOutAt := time.Unix(1_700_000_000, 0)
+	revocations := NewSessionRevocations(time.Hour)
+	revocations.now = func() time.Time { return loggedOutAt }
+	revocations.Revoke(&LogoutToken{Subject: "user-123", IssuedAt: loggedOutAt})
+
+	if !revocations.IsRevoked(&AuthClaims{Subject: "user-123", SessionID: "session-2", IssuedAt: loggedOutAt.Add(-time.Minute)}) {
+		t.Error("expected every earlier session of the subject to be revoked")
+	}
+	if revocations.IsRevoked(&AuthClaims{Subject: "user-456", IssuedAt: loggedOutAt.Add(-time.Minute)}) {
+		t.Error("expected other subjects to be unaffected")
+	}
+}
+
+func TestSessionRevocations_PrunesAfterRetention(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	revocations := NewSessionRevocations(time.Hour)
+	revocations.now = clock.now
+	revocations.Revoke(&LogoutToken{SessionID: "session-1", IssuedAt: clock.current})
+
+	clock.advance(time.Hour + time.Second)
+	revocations.Revoke(&LogoutToken{SessionID: "session-2", IssuedAt: clock.current})
+
+	if _, ok := revocations.sessions["session-1"]; ok {
+		t.Error("expected session-1 to be pruned")
+	}
+	if _, ok := revocations.sessions["session-2"]; !ok {
+		t.Error("expected session-2 to be retained")
+	}
+}
+
+func TestRevocationCheckingValidator(t *testing.T) {
+	loggedOutAt := time.Unix(1_700_000_000, 0)
+	revocations := NewSessionRevocations(time.Hour)
+	revocations.now = func() time.Time { return loggedOutAt }
+
+	next := &stubValidator{claims: &AuthClaims{
+		Subject:   "user-123",
+		SessionID: "session-1",
+		IssuedAt:  loggedOutAt.Add(-time.Minute),
+	}}
+	validator := NewRevocationCheckingValidator(next, revocations)
+
+	if _, err := validator.ValidateToken(context.Background(), "token"); err != nil {
+		t.Fatalf("ValidateToken() before logout error = %v", err)
+	}
+
+	revocations.Revoke(&LogoutToken{Subject: "user-123", SessionID: "session-1", IssuedAt: loggedOutAt})
+
+	if _, err := validator.ValidateToken(context.Background(), "token"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken() after logout error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := validator.ValidateTokenStrict(context.Background(), "token"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateTokenStrict() after logout error = %v, want ErrTokenRevoked", err)
+	}
+}
@@ -0,0 +1,36 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrCertificateBindingMismatch is returned when a certificate-bound token is presented
+// without the client certificate it is bound to
+var ErrCertificateBindingMismatch = errors.New("token is not bound to the presented client certificate")
+
+// CertificateThumbprint returns the x5t#S256 value of a certificate: base64url(SHA-256(DER))
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCertificateBinding checks a token's cnf.x5t#S256 claim against the client
+// certificate of the TLS connection (RFC 8705, section 3)
+// Tokens without a certificate binding pass regardless of the connection
+func VerifyCertificateBinding(claims *AuthClaims, state *tls.ConnectionState) error {
+	if claims.Confirmation.X5TS256 == "" {
+		return nil
+	}
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: no client certificate presented", ErrCertificateBindingMismatch)
+	}
+	if CertificateThumbprint(state.PeerCertificates[0]) != claims.Confirmation.X5TS256 {
+		return ErrCertificateBindingMismatch
+	}
+	return nil
+}
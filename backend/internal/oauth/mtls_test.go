@@ -0,0 +1,119 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// generateTestCertificate creates a self-signed client certificate
+func generateTestCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestVerifyCertificateBinding(t *testing.T) {
+	cert := generateTestCertificate(t, "reporting-service")
+	otherCert := generateTestCertificate(t, "other-service")
+	boundClaims := &AuthClaims{Subject: "service-account", Confirmation: Confirmation{X5TS256: CertificateThumbprint(cert)}}
+
+	tests := []struct {
+		name    string
+		claims  *AuthClaims
+		state   *tls.ConnectionState
+		wantErr bool
+	}{
+		{name: "Unbound token without TLS", claims: &AuthClaims{Subject: "user-123"}},
+		{
+			name:   "Unbound token with certificate",
+			claims: &AuthClaims{Subject: "user-123"},
+			state:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+		{
+			name:   "Bound token with matching certificate",
+			claims: boundClaims,
+			state:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+		{
+			name:    "Bound token with other certificate",
+			claims:  boundClaims,
+			state:   &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}},
+			wantErr: true,
+		},
+		{name: "Bound token without certificate", claims: boundClaims, state: &tls.ConnectionState{}, wantErr: true},
+		{name: "Bound token without TLS", claims: boundClaims, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyCertificateBinding(tt.claims, tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyCertificateBinding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrCertificateBindingMismatch) {
+				t.Errorf("VerifyCertificateBinding() error = %v, want %v", err, ErrCertificateBindingMismatch)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_CertificateBoundToken(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	validator, err := NewJWKSValidator(context.Background(), server.URL, server.URL)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	cert := generateTestCertificate(t, "reporting-service")
+	thumbprint := CertificateThumbprint(cert)
+	token := keyPair.createTestToken(t, jwt.MapClaims{
+		"sub": "service-account",
+		"iss": server.URL,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"cnf": map[string]any{"x5t#S256": thumbprint},
+	}, jwt.SigningMethodRS256)
+
+	claims, err := validator.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Confirmation.X5TS256 != thumbprint {
+		t.Errorf("X5TS256 = %v, want %v", claims.Confirmation.X5TS256, thumbprint)
+	}
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if err := VerifyCertificateBinding(claims, state); err != nil {
+		t.Errorf("VerifyCertificateBinding() error = %v", err)
+	}
+}
@@ -0,0 +1,485 @@
+// Package oauthtest provides an in-process fake of a Keycloak realm for tests and
+// offline development
+//
+// The fake serves the endpoints the backend uses - discovery, JWKS, token and
+// introspection - under the same paths as Keycloak, so a config.AuthConfig with
+// KeycloakURL set to Server.URL works unchanged in every validation mode.
+package oauthtest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// Defaults of a new Server
+const (
+	DefaultRealm        = "events"
+	DefaultClientID     = "events-api"
+	DefaultClientSecret = "events-api-secret"
+	DefaultTokenTTL     = 5 * time.Minute
+)
+
+// TokenOptions describes the claims of a minted token
+// Empty fields are omitted from the token, except where a default is noted
+type TokenOptions struct {
+	Subject   string   // sub; defaults to a random ID
+	Username  string   // preferred_username
+	Email     string   // email
+	ClientID  string   // azp; defaults to DefaultClientID
+	Audience  []string // aud; defaults to DefaultClientID
+	SessionID string   // sid
+	Scopes    []string // scope, space-separated in the token
+
+	Roles         []string            // realm_access.roles
+	ClientRoles   map[string][]string // resource_access.<client>.roles
+	Organizations []string            // organization, as Keycloak emits it by default
+
+	ExpiresIn time.Duration  // defaults to DefaultTokenTTL; negative for an expired token
+	Extra     map[string]any // additional claims, overriding the ones above
+}
+
+// Client is a confidential client registered with the fake realm
+type Client struct {
+	Secret string
+	Token  TokenOptions // claims of client_credentials tokens
+}
+
+// User is a user that can log in with the password grant
+type User struct {
+	Password string
+	Token    TokenOptions
+}
+
+// Algorithms lists the signing algorithms the server can use, see UseAlgorithm
+var Algorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// signingKey is a private key with its kid and algorithm
+type signingKey struct {
+	id  string
+	alg string
+	key crypto.Signer
+}
+
+// Server is a fake Keycloak realm backed by an httptest.Server
+// It is safe for concurrent use
+type Server struct {
+	*httptest.Server
+	Realm string
+
+	// BaseURL is the advertised base URL of issuers and endpoints, e.g. the address a
+	// fixed Listener was opened with; empty means the httptest URL
+	// It must be set before Start
+	BaseURL string
+
+	mu      sync.Mutex
+	alg     string       // algorithm of new keys
+	keys    []signingKey // the signing key first, then retired keys still published
+	clients map[string]Client
+	users   map[string]User
+	revoked map[string]bool // jti -> revoked
+	keySeq  int
+}
+
+// NewServer starts a fake realm with the DefaultClientID client registered
+// Callers must Close the server when done
+func NewServer(realm string) *Server {
+	s := NewUnstartedServer(realm)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer creates a fake realm that is not yet listening, e.g. to set a
+// fixed Listener and BaseURL before calling Start
+func NewUnstartedServer(realm string) *Server {
+	if realm == "" {
+		realm = DefaultRealm
+	}
+	s := &Server{
+		Realm:   realm,
+		alg:     "RS256",
+		clients: make(map[string]Client),
+		users:   make(map[string]User),
+		revoked: make(map[string]bool),
+	}
+	s.RotateKeys()
+	s.AddClient(DefaultClientID, DefaultClientSecret, TokenOptions{})
+
+	mux := http.NewServeMux()
+	prefix := "/realms/" + realm
+	mux.HandleFunc("GET "+prefix+"/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("GET "+prefix+"/protocol/openid-connect/certs", s.handleJWKS)
+	mux.HandleFunc("POST "+prefix+"/protocol/openid-connect/token", s.handleToken)
+	mux.HandleFunc("POST "+prefix+"/protocol/openid-connect/token/introspect", s.handleIntrospect)
+	s.Server = httptest.NewUnstartedServer(mux)
+	return s
+}
+
+// Issuer returns the iss claim of tokens minted by the server
+func (s *Server) Issuer() string {
+	return s.baseURL() + "/realms/" + s.Realm
+}
+
+// baseURL returns BaseURL, or the httptest URL if it is not set
+func (s *Server) baseURL() string {
+	if s.BaseURL != "" {
+		return s.BaseURL
+	}
+	return s.URL
+}
+
+// AuthConfig returns an AuthConfig pointing at the fake realm as DefaultClientID
+// The introspection cache is disabled, so revocations take effect immediately
+func (s *Server) AuthConfig() config.AuthConfig {
+	authConfig := config.DefaultConfig().Auth
+	authConfig.KeycloakURL = s.baseURL()
+	authConfig.RealmName = s.Realm
+	authConfig.ClientID = DefaultClientID
+	authConfig.ClientSecret = DefaultClientSecret
+	authConfig.IntrospectionCacheSize = 0
+	return authConfig
+}
+
+// AddClient registers a confidential client
+// token holds the claims of its client_credentials tokens; the subject defaults to
+// "service-account-<id>" and the azp to the client ID
+func (s *Server) AddClient(id, secret string, token TokenOptions) {
+	if token.Subject == "" {
+		token.Subject = "service-account-" + id
+	}
+	if token.ClientID == "" {
+		token.ClientID = id
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[id] = Client{Secret: secret, Token: token}
+}
+
+// AddUser registers a user for the password grant
+func (s *Server) AddUser(username, password string, token TokenOptions) {
+	if token.Username == "" {
+		token.Username = username
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = User{Password: password, Token: token}
+}
+
+// RotateKeys creates a new signing key using the current algorithm
+// The previous key stays in the JWKS, so tokens it signed remain valid
+func (s *Server) RotateKeys() {
+	s.mu.Lock()
+	alg := s.alg
+	s.mu.Unlock()
+	s.UseAlgorithm(alg)
+}
+
+// UseAlgorithm rotates to a new signing key for alg, which must be one of Algorithms
+// The previous key stays in the JWKS, so tokens it signed remain valid
+func (s *Server) UseAlgorithm(alg string) {
+	key, err := generateKey(alg)
+	if err != nil {
+		panic(fmt.Sprintf("oauthtest: failed to generate %s key: %v", alg, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alg = alg
+	s.keySeq++
+	s.keys = append([]signingKey{{id: fmt.Sprintf("key-%d", s.keySeq), alg: alg, key: key}}, s.keys...)
+	if len(s.keys) > 2 {
+		s.keys = s.keys[:2]
+	}
+}
+
+// MintToken returns an access token signed with the current key
+func (s *Server) MintToken(opts TokenOptions) string {
+	now := time.Now()
+	if opts.Subject == "" {
+		opts.Subject = randomID()
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = DefaultClientID
+	}
+	if len(opts.Audience) == 0 {
+		opts.Audience = []string{DefaultClientID}
+	}
+	if opts.ExpiresIn == 0 {
+		opts.ExpiresIn = DefaultTokenTTL
+	}
+
+	claims := jwt.MapClaims{
+		"iss": s.Issuer(),
+		"sub": opts.Subject,
+		"aud": opts.Audience,
+		"azp": opts.ClientID,
+		"typ": "Bearer",
+		"jti": randomID(),
+		"iat": now.Unix(),
+		"exp": now.Add(opts.ExpiresIn).Unix(),
+	}
+	setIfNotEmpty(claims, "preferred_username", opts.Username)
+	setIfNotEmpty(claims, "email", opts.Email)
+	setIfNotEmpty(claims, "sid", opts.SessionID)
+	setIfNotEmpty(claims, "scope", strings.Join(opts.Scopes, " "))
+	if len(opts.Roles) > 0 {
+		claims["realm_access"] = map[string]any{"roles": opts.Roles}
+	}
+	if len(opts.ClientRoles) > 0 {
+		resourceAccess := make(map[string]any, len(opts.ClientRoles))
+		for client, roles := range opts.ClientRoles {
+			resourceAccess[client] = map[string]any{"roles": roles}
+		}
+		claims["resource_access"] = resourceAccess
+	}
+	if len(opts.Organizations) > 0 {
+		claims["organization"] = opts.Organizations
+	}
+	for name, value := range opts.Extra {
+		claims[name] = value
+	}
+
+	s.mu.Lock()
+	key := s.keys[0]
+	s.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.key)
+	if err != nil {
+		// Signing with an in-memory key does not fail
+		panic(fmt.Sprintf("oauthtest: failed to sign token: %v", err))
+	}
+	return signed
+}
+
+// Revoke marks the token as revoked, so introspection reports it as inactive
+func (s *Server) Revoke(token string) {
+	if claims, err := s.parse(token); err == nil {
+		jti, _ := claims["jti"].(string)
+		s.mu.Lock()
+		s.revoked[jti] = true
+		s.mu.Unlock()
+	}
+}
+
+// handleDiscovery serves the OIDC discovery document
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	base := s.Issuer() + "/protocol/openid-connect"
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 s.Issuer(),
+		"jwks_uri":               base + "/certs",
+		"token_endpoint":         base + "/token",
+		"introspection_endpoint": base + "/token/introspect",
+		"grant_types_supported":  []string{"client_credentials", "password"},
+		// Like Keycloak, symmetric algorithms are advertised too
+		"id_token_signing_alg_values_supported": append([]string{"HS256", "HS384", "HS512"}, Algorithms...),
+	})
+}
+
+// handleJWKS serves the public signing keys
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	keys := make([]map[string]string, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.jwk())
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// handleToken issues tokens for the client_credentials and password grants
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	var opts TokenOptions
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		opts = client.Token
+	case "password":
+		s.mu.Lock()
+		user, ok := s.users[r.PostForm.Get("username")]
+		s.mu.Unlock()
+		if !ok || user.Password != r.PostForm.Get("password") {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		opts = user.Token
+		opts.ClientID = client.Token.ClientID
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Requested scopes are granted without consent checks
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		opts.Scopes = append(opts.Scopes, strings.Fields(scope)...)
+	}
+	if opts.ExpiresIn == 0 {
+		opts.ExpiresIn = DefaultTokenTTL
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": s.MintToken(opts),
+		"token_type":   "Bearer",
+		"expires_in":   int(opts.ExpiresIn.Seconds()),
+		"scope":        strings.Join(opts.Scopes, " "),
+	})
+}
+
+// handleIntrospect reports whether a token is active, honouring revocations
+func (s *Server) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authenticateClient(r); !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	claims, err := s.parse(r.PostForm.Get("token"))
+	if err != nil {
+		writeJSON(w, http.StatusOK, map[string]bool{"active": false})
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	s.mu.Lock()
+	revoked := s.revoked[jti]
+	s.mu.Unlock()
+	if revoked {
+		writeJSON(w, http.StatusOK, map[string]bool{"active": false})
+		return
+	}
+
+	// Keycloak returns the token claims plus a few introspection-specific members
+	response := map[string]any{"active": true, "token_type": "Bearer"}
+	for name, value := range claims {
+		response[name] = value
+	}
+	if azp, ok := claims["azp"]; ok {
+		response["client_id"] = azp
+	}
+	if username, ok := claims["preferred_username"]; ok {
+		response["username"] = username
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// authenticateClient checks client_secret_basic or client_secret_post credentials
+func (s *Server) authenticateClient(r *http.Request) (Client, bool) {
+	if err := r.ParseForm(); err != nil {
+		return Client{}, false
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[id]
+	if !ok || secret == "" || client.Secret != secret {
+		return Client{}, false
+	}
+	return client, true
+}
+
+// parse verifies a token signed by one of the published keys and returns its claims
+func (s *Server) parse(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, k := range s.keys {
+			if k.id == kid && k.alg == t.Method.Alg() {
+				return k.key.Public(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}, jwt.WithValidMethods(Algorithms), jwt.WithIssuer(s.Issuer()))
+	return claims, err
+}
+
+// jwk returns the public key as a JWK
+func (k signingKey) jwk() map[string]string {
+	jwk := map[string]string{"kid": k.id, "use": "sig", "alg": k.alg}
+	switch key := k.key.(type) {
+	case *rsa.PrivateKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PrivateKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = key.Curve.Params().Name
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk["y"] = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PrivateKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	}
+	return jwk
+}
+
+// generateKey creates a private key for the given algorithm
+func generateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// setIfNotEmpty sets the claim unless value is empty
+func setIfNotEmpty(claims jwt.MapClaims, name, value string) {
+	if value != "" {
+		claims[name] = value
+	}
+}
+
+// randomID returns a random identifier for sub and jti claims
+func randomID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,261 @@
+package oauthtest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postForm sends a form to the fake realm, optionally with basic client credentials
+func postForm(t *testing.T, s *Server, path string, form url.Values, basicUser, basicPass string) map[string]any {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, s.Issuer()+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	body["status"] = resp.StatusCode
+	return body
+}
+
+// introspect returns the introspection response for the token
+func introspect(t *testing.T, s *Server, token string) map[string]any {
+	t.Helper()
+	return postForm(t, s, "/protocol/openid-connect/token/introspect", url.Values{
+		"token":         {token},
+		"client_id":     {DefaultClientID},
+		"client_secret": {DefaultClientSecret},
+	}, "", "")
+}
+
+func TestServer_Discovery(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.Issuer() + "/.well-known/openid-configuration")
+	if err != nil {
+		t.Fatalf("GET discovery: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var doc map[string]any
+	json.NewDecoder(resp.Body).Decode(&doc)
+	if doc["issuer"] != s.Issuer() {
+		t.Errorf("issuer = %v, want %v", doc["issuer"], s.Issuer())
+	}
+	if doc["jwks_uri"] != s.Issuer()+"/protocol/openid-connect/certs" {
+		t.Errorf("jwks_uri = %v", doc["jwks_uri"])
+	}
+}
+
+func TestServer_BaseURL(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := NewUnstartedServer("")
+	s.Listener.Close()
+	s.Listener = listener
+	s.BaseURL = "http://" + listener.Addr().String()
+	s.Start()
+	defer s.Close()
+
+	resp, err := http.Get(s.BaseURL + "/realms/" + DefaultRealm + "/.well-known/openid-configuration")
+	if err != nil {
+		t.Fatalf("GET discovery: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var doc map[string]any
+	json.NewDecoder(resp.Body).Decode(&doc)
+	if want := s.BaseURL + "/realms/" + DefaultRealm; doc["issuer"] != want || s.Issuer() != want {
+		t.Errorf("issuer = %v, Issuer() = %v, want %v", doc["issuer"], s.Issuer(), want)
+	}
+	if s.AuthConfig().KeycloakURL != s.BaseURL {
+		t.Errorf("KeycloakURL = %v, want %v", s.AuthConfig().KeycloakURL, s.BaseURL)
+	}
+}
+
+func TestServer_TokenEndpoint(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+	s.AddClient("worker", "worker-secret", TokenOptions{Scopes: []string{"events:read"}})
+	s.AddUser("alice", "password", TokenOptions{Roles: []string{"system-admin"}})
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		basicUser      string
+		basicPass      string
+		expectedStatus int
+	}{
+		{
+			name:           "Client credentials with post",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {"worker"}, "client_secret": {"worker-secret"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Client credentials with basic",
+			form:           url.Values{"grant_type": {"client_credentials"}},
+			basicUser:      "worker",
+			basicPass:      "worker-secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wrong client secret",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {"worker"}, "client_secret": {"wrong"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Password grant",
+			form: url.Values{"grant_type": {"password"}, "username": {"alice"}, "password": {"password"},
+				"client_id": {DefaultClientID}, "client_secret": {DefaultClientSecret}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Wrong password",
+			form: url.Values{"grant_type": {"password"}, "username": {"alice"}, "password": {"nope"},
+				"client_id": {DefaultClientID}, "client_secret": {DefaultClientSecret}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Unsupported grant",
+			form:           url.Values{"grant_type": {"authorization_code"}, "client_id": {"worker"}, "client_secret": {"worker-secret"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := postForm(t, s, "/protocol/openid-connect/token", tt.form, tt.basicUser, tt.basicPass)
+			if body["status"] != tt.expectedStatus {
+				t.Fatalf("status = %v, want %v (%v)", body["status"], tt.expectedStatus, body)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			token, _ := body["access_token"].(string)
+			if active := introspect(t, s, token)["active"]; active != true {
+				t.Errorf("issued token active = %v, want true", active)
+			}
+		})
+	}
+}
+
+func TestServer_PasswordGrantClaims(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+	s.AddUser("alice", "password", TokenOptions{
+		Email:         "alice@example.com",
+		Roles:         []string{"system-admin"},
+		Organizations: []string{"acme"},
+	})
+
+	body := postForm(t, s, "/protocol/openid-connect/token", url.Values{
+		"grant_type": {"password"}, "username": {"alice"}, "password": {"password"}, "scope": {"events:read"},
+	}, DefaultClientID, DefaultClientSecret)
+	claims := introspect(t, s, body["access_token"].(string))
+
+	if claims["username"] != "alice" {
+		t.Errorf("username = %v, want alice", claims["username"])
+	}
+	if claims["client_id"] != DefaultClientID {
+		t.Errorf("client_id = %v, want %v", claims["client_id"], DefaultClientID)
+	}
+	if claims["scope"] != "events:read" {
+		t.Errorf("scope = %v, want events:read", claims["scope"])
+	}
+	if claims["email"] != "alice@example.com" {
+		t.Errorf("email = %v, want alice@example.com", claims["email"])
+	}
+	if orgs, _ := claims["organization"].([]any); len(orgs) != 1 || orgs[0] != "acme" {
+		t.Errorf("organization = %v, want [acme]", claims["organization"])
+	}
+}
+
+func TestServer_IntrospectionHonoursRevocation(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+
+	token := s.MintToken(TokenOptions{Subject: "user-1"})
+	other := s.MintToken(TokenOptions{Subject: "user-1"})
+	s.Revoke(token)
+
+	if active := introspect(t, s, token)["active"]; active != false {
+		t.Errorf("revoked token active = %v, want false", active)
+	}
+	if active := introspect(t, s, other)["active"]; active != true {
+		t.Errorf("other token active = %v, want true", active)
+	}
+}
+
+func TestServer_IntrospectionRejectsInvalidTokens(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+	foreign := NewServer("events")
+	defer foreign.Close()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "Expired", token: s.MintToken(TokenOptions{ExpiresIn: -time.Minute})},
+		{name: "Signed by another realm", token: foreign.MintToken(TokenOptions{})},
+		{name: "Malformed", token: "not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if active := introspect(t, s, tt.token)["active"]; active != false {
+				t.Errorf("active = %v, want false", active)
+			}
+		})
+	}
+}
+
+func TestServer_RotateKeys(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+
+	before := s.MintToken(TokenOptions{})
+	s.RotateKeys()
+	after := s.MintToken(TokenOptions{})
+	s.RotateKeys()
+
+	if active := introspect(t, s, after)["active"]; active != true {
+		t.Errorf("token of previous key active = %v, want true", active)
+	}
+	if active := introspect(t, s, before)["active"]; active != false {
+		t.Errorf("token of retired key active = %v, want false", active)
+	}
+}
+
+func TestServer_UseAlgorithm(t *testing.T) {
+	s := NewServer("events")
+	defer s.Close()
+
+	for _, alg := range Algorithms {
+		t.Run(alg, func(t *testing.T) {
+			s.UseAlgorithm(alg)
+			token := s.MintToken(TokenOptions{})
+
+			if active := introspect(t, s, token)["active"]; active != true {
+				t.Errorf("active = %v, want true", active)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"slices"
+	"sort"
+)
+
+// Organization is a Keycloak organization the subject is a member of
+type Organization struct {
+	Alias      string              // organization alias, the key of Keycloak's organization claim
+	ID         string              // organization id, empty unless the mapper adds it
+	Attributes map[string][]string // organization attributes, nil unless the mapper adds them
+}
+
+// parseOrganizations reads Keycloak's organization claim, which is either a list of
+// aliases, ["acme"], or - with "Add organization id" or "Add organization attributes"
+// enabled on the mapper - a map of alias to details, {"acme": {"id": "...", "region": ["eu"]}}
+func parseOrganizations(value any) []Organization {
+	switch v := value.(type) {
+	case string:
+		return []Organization{{Alias: v}}
+	case []any:
+		var orgs []Organization
+		for _, alias := range stringValues(v) {
+			orgs = append(orgs, Organization{Alias: alias})
+		}
+		return orgs
+	case map[string]any:
+		aliases := make([]string, 0, len(v))
+		for alias := range v {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+
+		orgs := make([]Organization, 0, len(aliases))
+		for _, alias := range aliases {
+			org := Organization{Alias: alias}
+			details, _ := v[alias].(map[string]any)
+			for name, detail := range details {
+				if name == "id" {
+					org.ID, _ = detail.(string)
+					continue
+				}
+				if org.Attributes == nil {
+					org.Attributes = make(map[string][]string)
+				}
+				org.Attributes[name] = stringValues(detail)
+			}
+			orgs = append(orgs, org)
+		}
+		return orgs
+	}
+	return nil
+}
+
+// allOrganizations returns the organizations found at all given paths, without duplicates
+func allOrganizations(payload map[string]any, paths []string) []Organization {
+	var orgs []Organization
+	for _, path := range paths {
+		for _, match := range lookupPath(payload, path) {
+			for _, org := range parseOrganizations(match.value) {
+				if !slices.ContainsFunc(orgs, func(o Organization) bool { return o.Alias == org.Alias }) {
+					orgs = append(orgs, org)
+				}
+			}
+		}
+	}
+	return orgs
+}
+
+// IsMemberOf checks if the subject is a member of the organization with the given id or alias
+func (c *AuthClaims) IsMemberOf(org string) bool {
+	_, ok := c.Organization(org)
+	return ok
+}
+
+// Organization returns the membership in the organization with the given id or alias
+func (c *AuthClaims) Organization(org string) (Organization, bool) {
+	if org == "" {
+		return Organization{}, false
+	}
+	for _, o := range c.Organizations {
+		if o.ID == org || o.Alias == org {
+			return o, true
+		}
+	}
+	return Organization{}, false
+}
+
+// OrganizationAliases returns the aliases of all organizations the subject is a member of
+func (c *AuthClaims) OrganizationAliases() []string {
+	aliases := make([]string, 0, len(c.Organizations))
+	for _, org := range c.Organizations {
+		aliases = append(aliases, org.Alias)
+	}
+	return aliases
+}
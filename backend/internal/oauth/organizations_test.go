@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+func TestParseOrganizations(t *testing.T) {
+	tests := []struct {
+		name  string
+		claim string
+		want  []Organization
+	}{
+		{name: "List form", claim: `["acme", "globex"]`, want: []Organization{{Alias: "acme"}, {Alias: "globex"}}},
+		{name: "Single alias", claim: `"acme"`, want: []Organization{{Alias: "acme"}}},
+		{
+			name:  "Map form with id",
+			claim: `{"globex": {"id": "org-2"}, "acme": {"id": "org-1"}}`,
+			want:  []Organization{{Alias: "acme", ID: "org-1"}, {Alias: "globex", ID: "org-2"}},
+		},
+		{
+			name:  "Map form with attributes",
+			claim: `{"acme": {"id": "org-1", "region": ["eu", "us"], "tier": "gold"}}`,
+			want: []Organization{{Alias: "acme", ID: "org-1", Attributes: map[string][]string{
+				"region": {"eu", "us"},
+				"tier":   {"gold"},
+			}}},
+		},
+		{name: "Map form without details", claim: `{"acme": {}}`, want: []Organization{{Alias: "acme"}}},
+		{name: "Unexpected type", claim: `42`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := decodeClaims([]byte(`{"organization": ` + tt.claim + `}`))
+			if err != nil {
+				t.Fatalf("decodeClaims() error = %v", err)
+			}
+			got := allOrganizations(payload, []string{"/organization"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allOrganizations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthClaims_IsMemberOf(t *testing.T) {
+	claims := &AuthClaims{Organizations: []Organization{
+		{Alias: "acme", ID: "org-1"},
+		{Alias: "globex"},
+	}}
+
+	tests := []struct {
+		org  string
+		want bool
+	}{
+		{org: "acme", want: true},
+		{org: "org-1", want: true},
+		{org: "globex", want: true},
+		{org: "initech", want: false},
+		{org: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.org, func(t *testing.T) {
+			if got := claims.IsMemberOf(tt.org); got != tt.want {
+				t.Errorf("IsMemberOf(%q) = %v, want %v", tt.org, got, tt.want)
+			}
+		})
+	}
+
+	if got := (&AuthClaims{}).IsMemberOf("acme"); got {
+		t.Errorf("IsMemberOf() without organizations = %v, want false", got)
+	}
+	if got, want := claims.OrganizationAliases(), []string{"acme", "globex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrganizationAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestOrganizations_BothValidators(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+
+	tests := []struct {
+		name  string
+		token string
+		want  []Organization
+	}{
+		{
+			name:  "List form",
+			token: realm.MintToken(oauthtest.TokenOptions{Organizations: []string{"acme"}}),
+			want:  []Organization{{Alias: "acme"}},
+		},
+		{
+			name: "Map form",
+			token: realm.MintToken(oauthtest.TokenOptions{Extra: map[string]any{
+				"organization": map[string]any{"acme": map[string]any{"id": "org-1", "region": []string{"eu"}}},
+			}}),
+			want: []Organization{{Alias: "acme", ID: "org-1", Attributes: map[string][]string{"region": {"eu"}}}},
+		},
+		{name: "No organization", token: realm.MintToken(oauthtest.TokenOptions{})},
+	}
+
+	for _, method := range []ValidationMethod{ValidationMethodIntrospection, ValidationMethodJWKS} {
+		validator, err := NewTokenValidator(method, ValidatorConfig{AuthConfig: realm.AuthConfig(), Context: context.Background()})
+		if err != nil {
+			t.Fatalf("NewTokenValidator(%s) error = %v", method, err)
+		}
+		for _, tt := range tests {
+			t.Run(string(method)+"/"+tt.name, func(t *testing.T) {
+				claims, err := validator.ValidateToken(context.Background(), tt.token)
+				if err != nil {
+					t.Fatalf("ValidateToken() error = %v", err)
+				}
+				if !reflect.DeepEqual(claims.Organizations, tt.want) {
+					t.Errorf("Organizations = %+v, want %+v", claims.Organizations, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultRevocationRetention is used when no retention is configured
+const defaultRevocationRetention = time.Hour
+
+// ErrTokenRevoked is returned when the token was revoked, either by a back-channel
+// logout of its session or subject or individually via the jti denylist
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// SessionRevocations records logged out sessions and subjects in memory
+// An entry revokes all tokens issued at or before its logout time; entries are
+// dropped after the retention, which must cover the longest access token lifetime
+type SessionRevocations struct {
+	mu        sync.RWMutex
+	sessions  map[string]time.Time // sid -> logout time
+	subjects  map[string]time.Time // sub -> logout time
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewSessionRevocations creates an empty SessionRevocations
+func NewSessionRevocations(retention time.Duration) *SessionRevocations {
+	if retention <= 0 {
+		retention = defaultRevocationRetention
+	}
+	return &SessionRevocations{
+		sessions:  make(map[string]time.Time),
+		subjects:  make(map[string]time.Time),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Revoke records a logout
+// A logout token with a sid ends only that session; one with just a sub ends all
+// sessions of the subject
+func (r *SessionRevocations) Revoke(logout *LogoutToken) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneLocked()
+	if logout.SessionID != "" {
+		r.sessions[logout.SessionID] = later(r.sessions[logout.SessionID], logout.IssuedAt)
+	} else {
+		r.subjects[logout.Subject] = later(r.subjects[logout.Subject], logout.IssuedAt)
+	}
+}
+
+// IsRevoked reports whether the token's session or subject was logged out at or after its iat
+// Tokens without iat are treated as revoked if their session or subject was logged out
+func (r *SessionRevocations) IsRevoked(claims *AuthClaims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if claims.SessionID != "" {
+		if loggedOutAt, ok := r.sessions[claims.SessionID]; ok && !claims.IssuedAt.After(loggedOutAt) {
+			return true
+		}
+	}
+	if claims.Subject != "" {
+		if loggedOutAt, ok := r.subjects[claims.Subject]; ok && !claims.IssuedAt.After(loggedOutAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneLocked drops entries older than the retention; the caller must hold the write lock
+func (r *SessionRevocations) pruneLocked() {
+	cutoff := r.now().Add(-r.retention)
+	for sid, loggedOutAt := range r.sessions {
+		if loggedOutAt.Before(cutoff) {
+			delete(r.sessions, sid)
+		}
+	}
+	for sub, loggedOutAt := range r.subjects {
+		if loggedOutAt.Before(cutoff) {
+			delete(r.subjects, sub)
+		}
+	}
+}
+
+// later returns the later of two times
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// RevocationCheckingValidator rejects tokens whose session or subject was logged out
+// via back-channel logout after the wrapped validator accepted them
+type RevocationCheckingValidator struct {
+	next        TokenValidator
+	revocations *SessionRevocations
+}
+
+// NewRevocationCheckingValidator wraps a TokenValidator with session revocation checks
+func NewRevocationCheckingValidator(next TokenValidator, revocations *SessionRevocations) *RevocationCheckingValidator {
+	return &RevocationCheckingValidator{next: next, revocations: revocations}
+}
+
+// ValidateToken validates the token and checks it has not been revoked
+func (v *RevocationCheckingValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	return v.check(v.next.ValidateToken(ctx, token))
+}
+
+// ValidateTokenStrict validates the token strictly if the wrapped validator supports it
+// and checks it has not been revoked
+func (v *RevocationCheckingValidator) ValidateTokenStrict(ctx context.Context, token string) (*AuthClaims, error) {
+	if strict, ok := v.next.(StrictTokenValidator); ok {
+		return v.check(strict.ValidateTokenStrict(ctx, token))
+	}
+	return v.ValidateToken(ctx, token)
+}
+
+// check rejects validated claims that belong to a revoked session or subject
+func (v *RevocationCheckingValidator) check(claims *AuthClaims, err error) (*AuthClaims, error) {
+	if err != nil {
+		return nil, err
+	}
+	if v.revocations.IsRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
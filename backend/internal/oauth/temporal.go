@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TemporalReason is the reason a token was rejected by its time-based claims
+type TemporalReason string
+
+const (
+	// TemporalReasonExpired means exp lies in the past, beyond the leeway
+	TemporalReasonExpired TemporalReason = "expired"
+	// TemporalReasonNotYetValid means nbf lies in the future, beyond the leeway
+	TemporalReasonNotYetValid TemporalReason = "not yet valid"
+	// TemporalReasonIssuedInFuture means iat lies in the future, beyond the leeway
+	TemporalReasonIssuedInFuture TemporalReason = "issued in the future"
+	// TemporalReasonTooOld means iat lies further in the past than the maximum token age
+	TemporalReasonTooOld TemporalReason = "too old"
+	// TemporalReasonMissingIssuedAt means a maximum token age is enforced but the token has no iat
+	TemporalReasonMissingIssuedAt TemporalReason = "missing iat"
+)
+
+// TemporalClaimError is returned when a token is rejected because of its exp, nbf or iat claim
+// It matches the corresponding jwt errors with errors.Is, e.g. jwt.ErrTokenExpired
+type TemporalClaimError struct {
+	Reason TemporalReason
+	Claim  string        // "exp", "nbf" or "iat"
+	Value  time.Time     // value of the claim, zero if missing
+	Now    time.Time     // time of the check
+	Leeway time.Duration // tolerated clock skew
+}
+
+// Error implements error
+func (e *TemporalClaimError) Error() string {
+	if e.Value.IsZero() {
+		return fmt.Sprintf("token %s", e.Reason)
+	}
+	return fmt.Sprintf("token %s: %s %s, now %s (leeway %s)",
+		e.Reason, e.Claim, e.Value.UTC().Format(time.RFC3339), e.Now.UTC().Format(time.RFC3339), e.Leeway)
+}
+
+// Is reports whether target is the jwt error for the same condition
+func (e *TemporalClaimError) Is(target error) bool {
+	switch e.Reason {
+	case TemporalReasonExpired:
+		return target == jwt.ErrTokenExpired
+	case TemporalReasonNotYetValid:
+		return target == jwt.ErrTokenNotValidYet
+	case TemporalReasonIssuedInFuture:
+		return target == jwt.ErrTokenUsedBeforeIssued
+	}
+	return false
+}
+
+// temporalPolicy checks a token's exp, nbf and iat claims
+// Zero times stand for missing claims, which are not checked, except for a
+// missing iat when a maximum age is enforced
+type temporalPolicy struct {
+	leeway time.Duration
+	maxAge time.Duration // 0 disables the age check
+	now    func() time.Time
+}
+
+// check returns a *TemporalClaimError if the token is not valid at the current time
+func (p temporalPolicy) check(exp, nbf, iat time.Time) error {
+	now := time.Now()
+	if p.now != nil {
+		now = p.now()
+	}
+	reject := func(reason TemporalReason, claim string, value time.Time) error {
+		return &TemporalClaimError{Reason: reason, Claim: claim, Value: value, Now: now, Leeway: p.leeway}
+	}
+
+	if !exp.IsZero() && !now.Before(exp.Add(p.leeway)) {
+		return reject(TemporalReasonExpired, "exp", exp)
+	}
+	if !nbf.IsZero() && now.Add(p.leeway).Before(nbf) {
+		return reject(TemporalReasonNotYetValid, "nbf", nbf)
+	}
+	if !iat.IsZero() && now.Add(p.leeway).Before(iat) {
+		return reject(TemporalReasonIssuedInFuture, "iat", iat)
+	}
+	if p.maxAge > 0 {
+		if iat.IsZero() {
+			return reject(TemporalReasonMissingIssuedAt, "iat", iat)
+		}
+		if now.Sub(iat) > p.maxAge+p.leeway {
+			return reject(TemporalReasonTooOld, "iat", iat)
+		}
+	}
+	return nil
+}
+
+// unixTime converts a NumericDate claim, or returns the zero time if it is missing
+func unixTime(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// numericDate converts a jwt.NumericDate, or returns the zero time if it is nil
+func numericDate(date *jwt.NumericDate) time.Time {
+	if date == nil {
+		return time.Time{}
+	}
+	return date.Time
+}
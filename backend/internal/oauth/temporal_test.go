@@ -0,0 +1,188 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+func TestTemporalPolicy_Check(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	policy := temporalPolicy{leeway: 30 * time.Second, maxAge: time.Hour, now: func() time.Time { return now }}
+
+	tests := []struct {
+		name           string
+		policy         temporalPolicy
+		exp, nbf, iat  time.Time
+		expectedReason TemporalReason // empty for a valid token
+	}{
+		{name: "Valid", policy: policy, exp: now.Add(time.Minute), nbf: now, iat: now},
+		{name: "Expired within leeway", policy: policy, exp: now.Add(-10 * time.Second), iat: now.Add(-time.Minute)},
+		{name: "Expired beyond leeway", policy: policy, exp: now.Add(-time.Minute), iat: now.Add(-time.Minute),
+			expectedReason: TemporalReasonExpired},
+		{name: "Not yet valid within leeway", policy: policy, nbf: now.Add(10 * time.Second), iat: now},
+		{name: "Not yet valid beyond leeway", policy: policy, nbf: now.Add(time.Minute), iat: now,
+			expectedReason: TemporalReasonNotYetValid},
+		{name: "Issued in the future within leeway", policy: policy, iat: now.Add(10 * time.Second)},
+		{name: "Issued in the future beyond leeway", policy: policy, iat: now.Add(time.Minute),
+			expectedReason: TemporalReasonIssuedInFuture},
+		{name: "Older than maximum age", policy: policy, exp: now.Add(time.Minute), iat: now.Add(-2 * time.Hour),
+			expectedReason: TemporalReasonTooOld},
+		{name: "Missing iat with maximum age", policy: policy, exp: now.Add(time.Minute),
+			expectedReason: TemporalReasonMissingIssuedAt},
+		{name: "Missing claims without maximum age", policy: temporalPolicy{now: policy.now}},
+		{name: "Expired without leeway", policy: temporalPolicy{now: policy.now}, exp: now,
+			expectedReason: TemporalReasonExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.check(tt.exp, tt.nbf, tt.iat)
+			if tt.expectedReason == "" {
+				if err != nil {
+					t.Errorf("check() error = %v, want nil", err)
+				}
+				return
+			}
+			var temporalErr *TemporalClaimError
+			if !errors.As(err, &temporalErr) {
+				t.Fatalf("check() error = %v, want *TemporalClaimError", err)
+			}
+			if temporalErr.Reason != tt.expectedReason {
+				t.Errorf("Reason = %q, want %q", temporalErr.Reason, tt.expectedReason)
+			}
+		})
+	}
+}
+
+func TestTemporalClaimError_Is(t *testing.T) {
+	tests := []struct {
+		reason TemporalReason
+		target error
+	}{
+		{reason: TemporalReasonExpired, target: jwt.ErrTokenExpired},
+		{reason: TemporalReasonNotYetValid, target: jwt.ErrTokenNotValidYet},
+		{reason: TemporalReasonIssuedInFuture, target: jwt.ErrTokenUsedBeforeIssued},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			err := error(&TemporalClaimError{Reason: tt.reason})
+			if !errors.Is(err, tt.target) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", err, tt.target)
+			}
+			if errors.Is(err, jwt.ErrTokenMalformed) {
+				t.Errorf("errors.Is(%v, jwt.ErrTokenMalformed) = true, want false", err)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_TemporalClaims(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	validator, err := NewJWKSValidator(context.Background(), server.URL, server.URL,
+		WithLeeway(30*time.Second), WithMaxTokenAge(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name           string
+		exp, nbf, iat  time.Time
+		expectedReason TemporalReason
+	}{
+		{name: "Expired within leeway", exp: now.Add(-10 * time.Second), iat: now.Add(-time.Minute)},
+		{name: "Clock of identity provider ahead", exp: now.Add(time.Minute), nbf: now.Add(10 * time.Second), iat: now.Add(10 * time.Second)},
+		{name: "Expired", exp: now.Add(-time.Minute), iat: now.Add(-time.Hour), expectedReason: TemporalReasonExpired},
+		{name: "Not yet valid", exp: now.Add(time.Hour), nbf: now.Add(time.Minute), iat: now, expectedReason: TemporalReasonNotYetValid},
+		{name: "Issued in the future", exp: now.Add(time.Hour), iat: now.Add(time.Minute), expectedReason: TemporalReasonIssuedInFuture},
+		{name: "Too old", exp: now.Add(time.Hour), iat: now.Add(-2 * time.Hour), expectedReason: TemporalReasonTooOld},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{"sub": "user-123", "iss": server.URL, "exp": tt.exp.Unix(), "iat": tt.iat.Unix()}
+			if !tt.nbf.IsZero() {
+				claims["nbf"] = tt.nbf.Unix()
+			}
+			token := keyPair.createTestToken(t, claims, jwt.SigningMethodRS256)
+
+			_, err := validator.ValidateToken(context.Background(), token)
+			var temporalErr *TemporalClaimError
+			switch {
+			case tt.expectedReason == "" && err != nil:
+				t.Errorf("ValidateToken() error = %v, want nil", err)
+			case tt.expectedReason != "" && !errors.As(err, &temporalErr):
+				t.Errorf("ValidateToken() error = %v, want *TemporalClaimError", err)
+			case tt.expectedReason != "" && temporalErr.Reason != tt.expectedReason:
+				t.Errorf("Reason = %q, want %q", temporalErr.Reason, tt.expectedReason)
+			}
+		})
+	}
+}
+
+func TestIntrospectToken_TemporalClaims(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name           string
+		response       TokenIntrospectionResponse
+		maxAge         time.Duration
+		expectedReason TemporalReason
+	}{
+		{
+			name:     "Active and current",
+			response: TokenIntrospectionResponse{Active: true, Exp: now.Add(time.Hour).Unix(), Iat: now.Unix()},
+		},
+		{
+			name:     "Expired within leeway",
+			response: TokenIntrospectionResponse{Active: true, Exp: now.Add(-10 * time.Second).Unix()},
+		},
+		{
+			name:           "Active but expired",
+			response:       TokenIntrospectionResponse{Active: true, Exp: now.Add(-time.Minute).Unix()},
+			expectedReason: TemporalReasonExpired,
+		},
+		{
+			name:           "Active but not yet valid",
+			response:       TokenIntrospectionResponse{Active: true, Exp: now.Add(time.Hour).Unix(), Nbf: now.Add(time.Minute).Unix()},
+			expectedReason: TemporalReasonNotYetValid,
+		},
+		{
+			name:           "Active but too old",
+			response:       TokenIntrospectionResponse{Active: true, Exp: now.Add(time.Hour).Unix(), Iat: now.Add(-2 * time.Hour).Unix()},
+			maxAge:         time.Hour,
+			expectedReason: TemporalReasonTooOld,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			authConfig := config.AuthConfig{
+				KeycloakURL:     "http://keycloak:8080",
+				RealmName:       "test-realm",
+				ClockSkewLeeway: 30 * time.Second,
+				MaxTokenAge:     tt.maxAge,
+			}
+
+			_, err := IntrospectToken(context.Background(), "token", authConfig, countingIntrospectionClient(&calls, tt.response))
+			var temporalErr *TemporalClaimError
+			switch {
+			case tt.expectedReason == "" && err != nil:
+				t.Errorf("IntrospectToken() error = %v, want nil", err)
+			case tt.expectedReason != "" && !errors.As(err, &temporalErr):
+				t.Errorf("IntrospectToken() error = %v, want *TemporalClaimError", err)
+			case tt.expectedReason != "" && temporalErr.Reason != tt.expectedReason:
+				t.Errorf("Reason = %q, want %q", temporalErr.Reason, tt.expectedReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// tokenCache is a size-bounded LRU cache of token validation results
+// Entries are keyed by a hash of the token so raw tokens are never held in memory
+type tokenCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	now        func() time.Time
+}
+
+// tokenCacheEntry is a single cached validation result
+type tokenCacheEntry struct {
+	key       string
+	claims    *AuthClaims
+	err       error
+	expiresAt time.Time
+}
+
+// newTokenCache creates a tokenCache holding at most maxEntries results
+func newTokenCache(maxEntries int) *tokenCache {
+	return &tokenCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of a token, used as cache key
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedResult is the outcome of a previous validation returned by tokenCache.get
+type cachedResult struct {
+	claims *AuthClaims
+	err    error
+}
+
+// get returns the cached result for key
+// The returned claims are a deep copy, so callers may modify them freely
+func (c *tokenCache) get(key string) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return cachedResult{}, false
+	}
+
+	entry := elem.Value.(*tokenCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(elem)
+		return cachedResult{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+	result := cachedResult{err: entry.err}
+	result.claims = entry.claims.clone()
+	return result, true
+}
+
+// set stores a result for key until expiresAt, evicting the least recently used
+// entry when the cache is full
+func (c *tokenCache) set(key string, claims *AuthClaims, err error, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.now().Before(expiresAt) {
+		return
+	}
+
+	claims = claims.clone()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*tokenCacheEntry)
+		entry.claims, entry.err, entry.expiresAt = claims, err, expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	for c.lru.Len() >= c.maxEntries && c.lru.Len() > 0 {
+		c.removeElement(c.lru.Back())
+	}
+
+	entry := &tokenCacheEntry{key: key, claims: claims, err: err, expiresAt: expiresAt}
+	c.entries[key] = c.lru.PushFront(entry)
+}
+
+// len returns the number of entries currently held, including expired ones
+func (c *tokenCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// removeElement deletes an element; the caller must hold c.mu
+func (c *tokenCache) removeElement(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*tokenCacheEntry)
+	delete(c.entries, entry.key)
+}
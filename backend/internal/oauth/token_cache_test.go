@@ -0,0 +1,233 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// fakeClock is a controllable time source for cache tests
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.current }
+
+func (c *fakeClock) advance(d time.Duration) { c.current = c.current.Add(d) }
+
+// countingIntrospectionClient returns the given response and counts calls
+func countingIntrospectionClient(calls *int, response TokenIntrospectionResponse) *MockHTTPClient {
+	responseBody, _ := json.Marshal(response)
+	return countingClient(calls, func(req *http.Request) (*http.Response, error) {
+		return createMockResponse(http.StatusOK, string(responseBody)), nil
+	})
+}
+
+// newCachingValidator creates an IntrospectionValidator with caching and a fake clock
+func newCachingValidator(client HTTPClient, clock *fakeClock, size int) *IntrospectionValidator {
+	authConfig := config.AuthConfig{
+		KeycloakURL:                   "http://keycloak:8080",
+		ClientID:                      "test-client",
+		ClientSecret:                  "test-secret",
+		RealmName:                     "test-realm",
+		IntrospectionCacheSize:        size,
+		IntrospectionCacheTTL:         time.Minute,
+		IntrospectionCacheNegativeTTL: 5 * time.Second,
+	}
+	validator, _ := NewIntrospectionValidator(authConfig, client)
+	validator.cache.now = clock.now
+	return validator
+}
+
+func TestTokenCache_GetSet(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	cache := newTokenCache(10)
+	cache.now = clock.now
+
+	cache.set("key", &AuthClaims{Subject: "user-123", Roles: []string{"org-user"}}, nil, clock.current.Add(time.Minute))
+
+	cached, ok := cache.get("key")
+	if !ok || cached.err != nil {
+		t.Fatalf("get() = %+v, %v; want hit", cached, ok)
+	}
+	if cached.claims.Subject != "user-123" {
+		t.Errorf("Subject = %v, want user-123", cached.claims.Subject)
+	}
+
+	// Mutating the returned claims must not affect the cached entry
+	cached.claims.Subject = "changed"
+	cached.claims.Roles[0] = "system-admin"
+	cached, _ = cache.get("key")
+	if cached.claims.Subject != "user-123" {
+		t.Errorf("cached Subject = %v after mutation, want user-123", cached.claims.Subject)
+	}
+	if cached.claims.Roles[0] != "org-user" {
+		t.Errorf("cached Roles = %v after mutation, want [org-user]", cached.claims.Roles)
+	}
+
+	clock.advance(time.Minute)
+	if _, ok := cache.get("key"); ok {
+		t.Error("get() returned an expired entry")
+	}
+	if cache.len() != 0 {
+		t.Errorf("len() = %d after expiry, want 0", cache.len())
+	}
+}
+
+func TestTokenCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	cache := newTokenCache(2)
+	cache.now = clock.now
+	expiry := clock.current.Add(time.Minute)
+
+	cache.set("a", &AuthClaims{Subject: "a"}, nil, expiry)
+	cache.set("b", &AuthClaims{Subject: "b"}, nil, expiry)
+	cache.get("a") // "b" is now least recently used
+	cache.set("c", &AuthClaims{Subject: "c"}, nil, expiry)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected 'b' to be evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("expected 'a' to be retained")
+	}
+	if _, ok := cache.get("c"); !ok {
+		t.Error("expected 'c' to be retained")
+	}
+}
+
+func TestTokenCache_IgnoresAlreadyExpiredEntries(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	cache := newTokenCache(10)
+	cache.now = clock.now
+
+	cache.set("key", &AuthClaims{}, nil, clock.current.Add(-time.Second))
+
+	if cache.len() != 0 {
+		t.Errorf("len() = %d, want 0", cache.len())
+	}
+}
+
+func TestHashToken_DoesNotContainToken(t *testing.T) {
+	token := "secret-token-value"
+	key := hashToken(token)
+
+	if key == token || bytes.Contains([]byte(key), []byte(token)) {
+		t.Error("hashToken() must not contain the raw token")
+	}
+	if key != hashToken(token) {
+		t.Error("hashToken() must be deterministic")
+	}
+}
+
+func TestIntrospectionValidator_CachesActiveResult(t *testing.T) {
+	clock := &fakeClock{current: time.Now()}
+	calls := 0
+	client := countingIntrospectionClient(&calls, TokenIntrospectionResponse{
+		Active: true,
+		Sub:    "user-123",
+		Scope:  "test-scope",
+		Exp:    clock.current.Add(time.Hour).Unix(),
+	})
+	validator := newCachingValidator(client, clock, 10)
+
+	for range 3 {
+		claims, err := validator.ValidateToken(context.Background(), "valid-token")
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		if claims.Subject != "user-123" {
+			t.Errorf("Subject = %v, want user-123", claims.Subject)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("introspection calls = %d, want 1", calls)
+	}
+
+	// After the TTL the token is introspected again
+	clock.advance(time.Minute)
+	if _, err := validator.ValidateToken(context.Background(), "valid-token"); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("introspection calls after TTL = %d, want 2", calls)
+	}
+}
+
+func TestIntrospectionValidator_CacheNeverOutlivesExp(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	client := countingIntrospectionClient(&calls, TokenIntrospectionResponse{
+		Active: true,
+		Sub:    "user-123",
+		Exp:    clock.current.Add(10 * time.Second).Unix(),
+	})
+	validator := newCachingValidator(client, clock, 10)
+
+	validator.ValidateToken(context.Background(), "short-lived-token")
+	clock.advance(10 * time.Second)
+	validator.ValidateToken(context.Background(), "short-lived-token")
+
+	if calls != 2 {
+		t.Errorf("introspection calls = %d, want 2 (entry must expire with the token)", calls)
+	}
+}
+
+func TestIntrospectionValidator_CachesInactiveResultWithNegativeTTL(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	client := countingIntrospectionClient(&calls, TokenIntrospectionResponse{Active: false})
+	validator := newCachingValidator(client, clock, 10)
+
+	for range 2 {
+		_, err := validator.ValidateToken(context.Background(), "inactive-token")
+		if !errors.Is(err, ErrTokenInactive) {
+			t.Fatalf("ValidateToken() error = %v, want ErrTokenInactive", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("introspection calls = %d, want 1", calls)
+	}
+
+	clock.advance(5 * time.Second)
+	validator.ValidateToken(context.Background(), "inactive-token")
+	if calls != 2 {
+		t.Errorf("introspection calls after negative TTL = %d, want 2", calls)
+	}
+}
+
+func TestIntrospectionValidator_DoesNotCacheTransportErrors(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("connection refused")
+		},
+	}
+	validator := newCachingValidator(client, clock, 10)
+
+	validator.ValidateToken(context.Background(), "any-token")
+	validator.ValidateToken(context.Background(), "any-token")
+
+	if calls != 2 {
+		t.Errorf("introspection calls = %d, want 2", calls)
+	}
+}
+
+func TestIntrospectionValidator_CacheDisabledBySize(t *testing.T) {
+	validator := mustIntrospectionValidator(t, config.AuthConfig{
+		IntrospectionCacheSize: 0,
+		IntrospectionCacheTTL:  time.Minute,
+	}, nil)
+
+	if validator.cache != nil {
+		t.Error("expected cache to be disabled when size is 0")
+	}
+}
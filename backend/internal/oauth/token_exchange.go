@@ -0,0 +1,133 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// Token exchange grant and token type identifiers (RFC 8693, section 3)
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// ErrNoSubjectToken is returned when a request carries no validated access token to exchange
+var ErrNoSubjectToken = errors.New("no validated access token in request context")
+
+// exchangedToken is a cached token exchange result
+type exchangedToken struct {
+	token     *Token
+	refreshAt time.Time
+}
+
+// TokenExchanger exchanges validated user tokens for tokens audienced to a
+// downstream service, so user tokens are never forwarded as-is
+// Results are cached per subject, audience and scopes
+type TokenExchanger struct {
+	authenticator ClientAuthenticator
+	client        HTTPClient
+	endpoints     EndpointResolver
+	now           func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]exchangedToken
+}
+
+// NewTokenExchanger creates a TokenExchanger that authenticates as AuthConfig.ClientID
+// The client must be allowed to exchange tokens for the target audiences in Keycloak
+// It fails if the client authentication configured in AuthConfig is invalid
+func NewTokenExchanger(authConfig config.AuthConfig, client HTTPClient) (*TokenExchanger, error) {
+	if client == nil {
+		client = defaultHTTPClient()
+	}
+	authenticator, err := NewClientAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenExchanger{
+		authenticator: authenticator,
+		client:        client,
+		endpoints:     NewEndpointResolver(authConfig, client),
+		now:           time.Now,
+		cache:         make(map[string]exchangedToken),
+	}, nil
+}
+
+// ExchangeForRequest exchanges the access token validated by the auth middleware
+// for a token with the given audience and scopes
+func (e *TokenExchanger) ExchangeForRequest(r *http.Request, audience string, scopes ...string) (*Token, error) {
+	token := GetAccessToken(r)
+	claims := GetAuthClaims(r)
+	if token == "" || claims == nil {
+		return nil, ErrNoSubjectToken
+	}
+	return e.Exchange(r.Context(), token, claims, audience, scopes...)
+}
+
+// Exchange exchanges subjectToken, whose validated claims are given, for a token with
+// the given audience and scopes
+// Tokens are cached per subject token, audience and scopes, and reused until shortly
+// before expiry and never past the subject token's exp
+func (e *TokenExchanger) Exchange(ctx context.Context, subjectToken string, claims *AuthClaims, audience string, scopes ...string) (*Token, error) {
+	// The exchanged token depends on the subject token's client (azp) and session as
+	// well, so iss and sub alone would hand it to other clients of the same user
+	sortedScopes := slices.Sorted(slices.Values(scopes))
+	key := hashToken(subjectToken) + "|" + audience + "|" + strings.Join(sortedScopes, " ")
+
+	// The lock is not held during the exchange, so one slow audience does not block others
+	now := e.now()
+	e.mu.Lock()
+	cached, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && now.Before(cached.refreshAt) {
+		return cached.token, nil
+	}
+
+	endpoints, err := e.endpoints.Endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token endpoint: %w", err)
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", tokenExchangeGrantType)
+	data.Set("subject_token", subjectToken)
+	data.Set("subject_token_type", accessTokenType)
+	data.Set("requested_token_type", accessTokenType)
+	data.Set("audience", audience)
+	if len(sortedScopes) > 0 {
+		data.Set("scope", strings.Join(sortedScopes, " "))
+	}
+	token, err := requestToken(ctx, endpoints.TokenURL, data, e.authenticator, e.client, now)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange for audience %s failed: %w", audience, err)
+	}
+
+	refreshAt := refreshTime(token, now)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(refreshAt) {
+		refreshAt = claims.ExpiresAt
+	}
+	e.mu.Lock()
+	e.pruneLocked(now)
+	e.cache[key] = exchangedToken{token: token, refreshAt: refreshAt}
+	e.mu.Unlock()
+
+	return token, nil
+}
+
+// pruneLocked drops cached tokens that are due for refresh
+func (e *TokenExchanger) pruneLocked(now time.Time) {
+	for key, cached := range e.cache {
+		if !now.Before(cached.refreshAt) {
+			delete(e.cache, key)
+		}
+	}
+}
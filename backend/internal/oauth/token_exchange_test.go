@@ -0,0 +1,145 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// mustTokenExchanger creates a TokenExchanger, failing the test on error
+func mustTokenExchanger(t *testing.T, authConfig config.AuthConfig, client HTTPClient) *TokenExchanger {
+	t.Helper()
+	exchanger, err := NewTokenExchanger(authConfig, client)
+	if err != nil {
+		t.Fatalf("NewTokenExchanger() error = %v", err)
+	}
+	return exchanger
+}
+
+func TestTokenExchanger_Request(t *testing.T) {
+	calls := 0
+	var form map[string]string
+	exchanger := mustTokenExchanger(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, &form))
+	claims := &AuthClaims{Subject: "user-123", ExpiresAt: time.Now().Add(time.Hour)}
+
+	token, err := exchanger.Exchange(context.Background(), "user-token", claims, "reporting-api", "reports:read", "events:read")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Errorf("AccessToken = %v, want token-1", token.AccessToken)
+	}
+
+	want := map[string]string{
+		"grant_type":           "urn:ietf:params:oauth:grant-type:token-exchange",
+		"subject_token":        "user-token",
+		"subject_token_type":   "urn:ietf:params:oauth:token-type:access_token",
+		"requested_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		"audience":             "reporting-api",
+		"scope":                "events:read reports:read",
+		"client_id":            "events-api",
+	}
+	for key, value := range want {
+		if form[key] != value {
+			t.Errorf("form[%s] = %q, want %q", key, form[key], value)
+		}
+	}
+}
+
+func TestTokenExchanger_CachesPerSubjectTokenAndAudience(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	exchanger := mustTokenExchanger(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, nil))
+	exchanger.now = clock.now
+
+	alice := &AuthClaims{Subject: "alice", ExpiresAt: clock.current.Add(time.Hour)}
+	bob := &AuthClaims{Subject: "bob", ExpiresAt: clock.current.Add(time.Hour)}
+
+	steps := []struct {
+		subjectToken string
+		claims       *AuthClaims
+		audience     string
+		scopes       []string
+		wantToken    string
+	}{
+		{subjectToken: "alice-token", claims: alice, audience: "reporting-api", wantToken: "token-1"},
+		{subjectToken: "alice-token", claims: alice, audience: "reporting-api", wantToken: "token-1"},
+		{subjectToken: "alice-token", claims: alice, audience: "billing-api", wantToken: "token-2"},
+		{subjectToken: "bob-token", claims: bob, audience: "reporting-api", wantToken: "token-3"},
+		{subjectToken: "alice-token", claims: alice, audience: "reporting-api", scopes: []string{"reports:write"}, wantToken: "token-4"},
+		// Same subject, but a token issued to another client
+		{subjectToken: "alice-cli-token", claims: alice, audience: "reporting-api", wantToken: "token-5"},
+	}
+
+	for i, step := range steps {
+		token, err := exchanger.Exchange(context.Background(), step.subjectToken, step.claims, step.audience, step.scopes...)
+		if err != nil {
+			t.Fatalf("step %d: Exchange() error = %v", i, err)
+		}
+		if token.AccessToken != step.wantToken {
+			t.Errorf("step %d: AccessToken = %v, want %v", i, token.AccessToken, step.wantToken)
+		}
+	}
+}
+
+func TestTokenExchanger_CacheBoundedBySubjectTokenExpiry(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	exchanger := mustTokenExchanger(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, nil))
+	exchanger.now = clock.now
+	claims := &AuthClaims{Subject: "alice", ExpiresAt: clock.current.Add(time.Minute)}
+
+	exchanger.Exchange(context.Background(), "user-token", claims, "reporting-api")
+	clock.advance(time.Minute)
+	exchanger.Exchange(context.Background(), "user-token", claims, "reporting-api")
+
+	if calls != 2 {
+		t.Errorf("token requests = %d, want 2", calls)
+	}
+}
+
+func TestTokenExchanger_ExchangeForRequest(t *testing.T) {
+	calls := 0
+	exchanger := mustTokenExchanger(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	if _, err := exchanger.ExchangeForRequest(req, "reporting-api"); !errors.Is(err, ErrNoSubjectToken) {
+		t.Errorf("ExchangeForRequest() error = %v, want %v", err, ErrNoSubjectToken)
+	}
+
+	req = SetAuthClaims(req, &AuthClaims{Subject: "alice"})
+	req = SetAccessToken(req, "user-token")
+	token, err := exchanger.ExchangeForRequest(req, "reporting-api")
+	if err != nil {
+		t.Fatalf("ExchangeForRequest() error = %v", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Errorf("AccessToken = %v, want token-1", token.AccessToken)
+	}
+}
+
+func TestIntrospectToken_ActorChain(t *testing.T) {
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			body := `{"active":true,"sub":"alice","act":{"sub":"service-account-events-api","act":{"sub":"service-account-gateway"}}}`
+			return createMockResponse(http.StatusOK, body), nil
+		},
+	}
+
+	claims, err := IntrospectToken(context.Background(), "delegated-token", umaTestConfig(), client)
+	if err != nil {
+		t.Fatalf("IntrospectToken(context.Background(), ) error = %v", err)
+	}
+	chain := claims.ActorChain()
+	if len(chain) != 2 || chain[0].Subject != "service-account-events-api" || chain[1].Subject != "service-account-gateway" {
+		t.Errorf("ActorChain() = %+v, want events-api then gateway", chain)
+	}
+	if (&AuthClaims{Subject: "alice"}).ActorChain() != nil {
+		t.Error("ActorChain() of an undelegated token should be nil")
+	}
+}
@@ -0,0 +1,224 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is replaced
+// Tokens with a short lifetime are refreshed after half of it instead
+const tokenRefreshMargin = 30 * time.Second
+
+// Token is an access token obtained from the token endpoint
+type Token struct {
+	AccessToken string
+	TokenType   string
+	ExpiresAt   time.Time // zero if the token endpoint did not report expires_in
+}
+
+// tokenResponse represents a successful token endpoint response (RFC 6749, section 5.1)
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	IssuedTokenType string `json:"issued_token_type"`
+}
+
+// tokenErrorResponse represents a token endpoint error response (RFC 6749, section 5.2)
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// TokenSource supplies access tokens for outbound requests
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// requestToken posts a grant to the token endpoint, authenticating with authenticator
+func requestToken(ctx context.Context, tokenURL string, data url.Values, authenticator ClientAuthenticator, client HTTPClient, now time.Time) (*Token, error) {
+	req, err := newClientAuthRequest(ctx, tokenURL, data, authenticator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	// Calling the token endpoint
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var tokenErr tokenErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&tokenErr) == nil && tokenErr.Error != "" {
+			return nil, fmt.Errorf("token request returned status %d: %s %s", resp.StatusCode, tokenErr.Error, tokenErr.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, errors.New("token response contains no access_token")
+	}
+
+	token := &Token{AccessToken: tokenResp.AccessToken, TokenType: tokenResp.TokenType}
+	if tokenResp.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// ClientCredentialsTokenSource obtains tokens for the service itself using the
+// client_credentials grant and caches them until shortly before expiry
+// It is safe for concurrent use; concurrent callers share a single token request
+type ClientCredentialsTokenSource struct {
+	authenticator ClientAuthenticator
+	client        HTTPClient
+	endpoints     EndpointResolver
+	scopes        []string
+	now           func() time.Time
+
+	mu        sync.Mutex
+	token     *Token
+	refreshAt time.Time
+}
+
+// NewClientCredentialsTokenSource creates a ClientCredentialsTokenSource for
+// AuthConfig.ClientID, requesting the given scopes
+// It fails if the client authentication configured in AuthConfig is invalid
+func NewClientCredentialsTokenSource(authConfig config.AuthConfig, client HTTPClient, scopes ...string) (*ClientCredentialsTokenSource, error) {
+	if client == nil {
+		client = defaultHTTPClient()
+	}
+	authenticator, err := NewClientAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientCredentialsTokenSource{
+		authenticator: authenticator,
+		client:        client,
+		endpoints:     NewEndpointResolver(authConfig, client),
+		scopes:        scopes,
+		now:           time.Now,
+	}, nil
+}
+
+// Token returns the cached token, requesting a new one if it is due for refresh
+// If the refresh fails, a cached token that has not yet expired is still returned
+func (s *ClientCredentialsTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != nil && now.Before(s.refreshAt) {
+		return s.token, nil
+	}
+
+	token, err := s.fetch(ctx, now)
+	if err != nil {
+		if s.token != nil && (s.token.ExpiresAt.IsZero() || now.Before(s.token.ExpiresAt)) {
+			return s.token, nil
+		}
+		return nil, err
+	}
+
+	s.token = token
+	s.refreshAt = refreshTime(token, now)
+	return token, nil
+}
+
+// Invalidate drops the cached token, e.g. after a resource server rejected it
+func (s *ClientCredentialsTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = nil
+}
+
+// fetch requests a new token from the token endpoint
+func (s *ClientCredentialsTokenSource) fetch(ctx context.Context, now time.Time) (*Token, error) {
+	endpoints, err := s.endpoints.Endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token endpoint: %w", err)
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+	if len(s.scopes) > 0 {
+		data.Set("scope", strings.Join(s.scopes, " "))
+	}
+	return requestToken(ctx, endpoints.TokenURL, data, s.authenticator, s.client, now)
+}
+
+// refreshTime returns when a token obtained at now should be replaced
+// Tokens without an expiry are refreshed after tokenRefreshMargin
+func refreshTime(token *Token, now time.Time) time.Time {
+	if token.ExpiresAt.IsZero() {
+		return now.Add(tokenRefreshMargin)
+	}
+	lifetime := token.ExpiresAt.Sub(now)
+	if lifetime <= 2*tokenRefreshMargin {
+		return now.Add(lifetime / 2)
+	}
+	return token.ExpiresAt.Add(-tokenRefreshMargin)
+}
+
+// tokenInvalidator is implemented by token sources that can drop a rejected token
+type tokenInvalidator interface {
+	Invalidate()
+}
+
+// Transport is an http.RoundTripper that authenticates requests with a token from Source
+type Transport struct {
+	Source TokenSource
+	Base   http.RoundTripper // defaults to http.DefaultTransport
+}
+
+// RoundTrip adds the Authorization header and forwards the request to Base
+// A 401 response invalidates the token, so the next request obtains a new one
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to obtain access token: %w", err)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	authReq := req.Clone(req.Context())
+	authReq.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(authReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		if invalidator, ok := t.Source.(tokenInvalidator); ok {
+			invalidator.Invalidate()
+		}
+	}
+	return resp, nil
+}
+
+// NewTokenSourceClient returns an http.Client that authenticates every request
+// with a token from source
+func NewTokenSourceClient(source TokenSource) *http.Client {
+	return &http.Client{Transport: &Transport{Source: source}}
+}
@@ -0,0 +1,206 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// mustClientCredentialsTokenSource creates a ClientCredentialsTokenSource, failing the test on error
+func mustClientCredentialsTokenSource(t *testing.T, authConfig config.AuthConfig, client HTTPClient, scopes ...string) *ClientCredentialsTokenSource {
+	t.Helper()
+	source, err := NewClientCredentialsTokenSource(authConfig, client, scopes...)
+	if err != nil {
+		t.Fatalf("NewClientCredentialsTokenSource() error = %v", err)
+	}
+	return source
+}
+
+// tokenEndpointClient issues numbered tokens with the given lifetime and records the last form
+// It fails with a transport error while *fail is true
+func tokenEndpointClient(calls *int, fail *bool, expiresIn int, form *map[string]string) *MockHTTPClient {
+	return countingClient(calls, func(req *http.Request) (*http.Response, error) {
+		if fail != nil && *fail {
+			return nil, errors.New("connection refused")
+		}
+		if form != nil {
+			req.ParseForm()
+			*form = map[string]string{}
+			for key := range req.PostForm {
+				(*form)[key] = req.PostForm.Get(key)
+			}
+		}
+		body := fmt.Sprintf(`{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, *calls, expiresIn)
+		return createMockResponse(http.StatusOK, body), nil
+	})
+}
+
+func TestClientCredentialsTokenSource_Request(t *testing.T) {
+	calls := 0
+	var form map[string]string
+	authConfig := umaTestConfig()
+	authConfig.ClientSecret = "secret"
+	source := mustClientCredentialsTokenSource(t, authConfig, tokenEndpointClient(&calls, nil, 300, &form), "events:read", "profile")
+
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Errorf("AccessToken = %v, want token-1", token.AccessToken)
+	}
+
+	want := map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     "events-api",
+		"client_secret": "secret",
+		"scope":         "events:read profile",
+	}
+	for key, value := range want {
+		if form[key] != value {
+			t.Errorf("form[%s] = %q, want %q", key, form[key], value)
+		}
+	}
+}
+
+func TestClientCredentialsTokenSource_CachesUntilRefresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		expiresIn    int
+		cachedFor    time.Duration
+		refreshAfter time.Duration
+	}{
+		{name: "Refreshed before expiry", expiresIn: 300, cachedFor: 269 * time.Second, refreshAfter: 270 * time.Second},
+		{name: "Short-lived token refreshed at half lifetime", expiresIn: 40, cachedFor: 19 * time.Second, refreshAfter: 20 * time.Second},
+		{name: "No expiry reported", expiresIn: 0, cachedFor: tokenRefreshMargin - time.Second, refreshAfter: tokenRefreshMargin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+			calls := 0
+			source := mustClientCredentialsTokenSource(t, umaTestConfig(), tokenEndpointClient(&calls, nil, tt.expiresIn, nil))
+			source.now = clock.now
+
+			source.Token(context.Background())
+			clock.advance(tt.cachedFor)
+			token, _ := source.Token(context.Background())
+			if calls != 1 || token.AccessToken != "token-1" {
+				t.Errorf("calls = %d, token = %v; want 1, token-1", calls, token.AccessToken)
+			}
+
+			clock.advance(tt.refreshAfter - tt.cachedFor)
+			token, _ = source.Token(context.Background())
+			if calls != 2 || token.AccessToken != "token-2" {
+				t.Errorf("calls after refresh = %d, token = %v; want 2, token-2", calls, token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsTokenSource_RefreshFailure(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	fail := false
+	source := mustClientCredentialsTokenSource(t, umaTestConfig(), tokenEndpointClient(&calls, &fail, 300, nil))
+	source.now = clock.now
+
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	// Within the refresh margin the still valid token is returned
+	fail = true
+	clock.advance(280 * time.Second)
+	token, err := source.Token(context.Background())
+	if err != nil || token.AccessToken != "token-1" {
+		t.Errorf("Token() = %v, %v; want token-1", token, err)
+	}
+
+	// Once expired, the failure is reported
+	clock.advance(20 * time.Second)
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Error("Token() expected error after expiry")
+	}
+}
+
+func TestClientCredentialsTokenSource_ErrorResponse(t *testing.T) {
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(http.StatusUnauthorized, `{"error":"unauthorized_client","error_description":"Invalid client secret"}`), nil
+		},
+	}
+	source := mustClientCredentialsTokenSource(t, umaTestConfig(), client)
+
+	_, err := source.Token(context.Background())
+	if err == nil {
+		t.Fatal("Token() expected error")
+	}
+	if want := "token request returned status 401: unauthorized_client Invalid client secret"; err.Error() != want {
+		t.Errorf("Token() error = %q, want %q", err, want)
+	}
+}
+
+func TestClientCredentialsTokenSource_Concurrent(t *testing.T) {
+	calls := 0
+	source := mustClientCredentialsTokenSource(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, nil))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := source.Token(context.Background()); err != nil {
+				t.Errorf("Token() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("token requests = %d, want 1", calls)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var authorizations []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	calls := 0
+	source := mustClientCredentialsTokenSource(t, umaTestConfig(), tokenEndpointClient(&calls, nil, 300, nil))
+	client := NewTokenSourceClient(source)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	if req.Header.Get("Authorization") != "" {
+		t.Error("Transport modified the caller's request")
+	}
+
+	// A rejected token is replaced on the next request
+	status = http.StatusUnauthorized
+	resp, _ = client.Get(server.URL)
+	resp.Body.Close()
+	resp, _ = client.Get(server.URL)
+	resp.Body.Close()
+
+	want := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}
+	if fmt.Sprint(authorizations) != fmt.Sprint(want) {
+		t.Errorf("Authorization headers = %v, want %v", authorizations, want)
+	}
+}
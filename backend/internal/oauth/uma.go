@@ -0,0 +1,134 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// umaTicketGrantType is the grant type for Keycloak Authorization Services permission requests
+const umaTicketGrantType = "urn:ietf:params:oauth:grant-type:uma-ticket"
+
+// defaultPermissionCacheSize bounds the decision cache when no size is configured
+const defaultPermissionCacheSize = 1000
+
+// errPermissionDenied marks a cached negative decision
+var errPermissionDenied = errors.New("permission denied")
+
+// permissionDecisionResponse represents the token endpoint response for response_mode=decision
+type permissionDecisionResponse struct {
+	Result bool `json:"result"`
+}
+
+// RequestPermissionDecision asks Keycloak Authorization Services at the given token endpoint
+// whether the access token is granted the given scope on the given resource of the
+// resource server (AuthConfig.ClientID)
+// A denial is reported as false with a nil error
+func RequestPermissionDecision(ctx context.Context, tokenURL, token, resource, scope string, authConfig config.AuthConfig, client HTTPClient) (bool, error) {
+	permission := resource
+	if scope != "" {
+		permission += "#" + scope
+	}
+
+	// Prepare the permission request
+	data := url.Values{}
+	data.Set("grant_type", umaTicketGrantType)
+	data.Set("audience", authConfig.ClientID)
+	data.Set("permission", permission)
+	data.Set("response_mode", "decision")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to create permission request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// Calling the token endpoint
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("permission request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Keycloak answers 403 (access_denied) when the permission is not granted
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("permission request returned status %d", resp.StatusCode)
+	}
+
+	var decision permissionDecisionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("failed to decode permission response: %w", err)
+	}
+
+	return decision.Result, nil
+}
+
+// PermissionEvaluator obtains permission decisions from Keycloak and caches them per token
+type PermissionEvaluator struct {
+	authConfig config.AuthConfig
+	client     HTTPClient
+	endpoints  EndpointResolver
+	cache      *tokenCache
+	ttl        time.Duration
+}
+
+// NewPermissionEvaluator creates a PermissionEvaluator
+// Decisions are cached for PolicyDecisionCacheTTL and never past the token's exp
+func NewPermissionEvaluator(authConfig config.AuthConfig, client HTTPClient) *PermissionEvaluator {
+	if client == nil {
+		client = defaultHTTPClient()
+	}
+	size := authConfig.PolicyDecisionCacheSize
+	if size <= 0 {
+		size = defaultPermissionCacheSize
+	}
+	return &PermissionEvaluator{
+		authConfig: authConfig,
+		client:     client,
+		endpoints:  NewEndpointResolver(authConfig, client),
+		cache:      newTokenCache(size),
+		ttl:        authConfig.PolicyDecisionCacheTTL,
+	}
+}
+
+// HasPermission reports whether the token grants scope on resource
+// claims are those of the already validated token and bound the cache lifetime
+func (e *PermissionEvaluator) HasPermission(ctx context.Context, token string, claims *AuthClaims, resource, scope string) (bool, error) {
+	key := hashToken(token) + "|" + resource + "#" + scope
+	if cached, ok := e.cache.get(key); ok {
+		return cached.err == nil, nil
+	}
+
+	endpoints, err := e.endpoints.Endpoints(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve token endpoint: %w", err)
+	}
+	granted, err := RequestPermissionDecision(ctx, endpoints.TokenURL, token, resource, scope, e.authConfig, e.client)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := e.cache.now().Add(e.ttl)
+	if claims != nil && !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+	if granted {
+		e.cache.set(key, &AuthClaims{}, nil, expiresAt)
+	} else {
+		e.cache.set(key, nil, errPermissionDenied, expiresAt)
+	}
+
+	return granted, nil
+}
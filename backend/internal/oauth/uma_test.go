@@ -0,0 +1,178 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// testTokenURL is the token endpoint derived from umaTestConfig
+const testTokenURL = "http://keycloak:8080/realms/test-realm/protocol/openid-connect/token"
+
+// umaTestConfig returns an AuthConfig for permission decision tests
+func umaTestConfig() config.AuthConfig {
+	return config.AuthConfig{
+		KeycloakURL:             "http://keycloak:8080",
+		ClientID:                "events-api",
+		RealmName:               "test-realm",
+		PolicyDecisionCacheSize: 10,
+		PolicyDecisionCacheTTL:  time.Minute,
+	}
+}
+
+// decisionClient answers permission requests with the given status and body and counts calls
+func decisionClient(calls *int, status int, body string) *MockHTTPClient {
+	return countingClient(calls, func(req *http.Request) (*http.Response, error) {
+		return createMockResponse(status, body), nil
+	})
+}
+
+func TestRequestPermissionDecision_Request(t *testing.T) {
+	var captured *http.Request
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			captured = req
+			if err := req.ParseForm(); err != nil {
+				t.Fatalf("ParseForm() error = %v", err)
+			}
+			return createMockResponse(http.StatusOK, `{"result":true}`), nil
+		},
+	}
+
+	granted, err := RequestPermissionDecision(context.Background(), testTokenURL, "access-token", "events", "read", umaTestConfig(), client)
+	if err != nil {
+		t.Fatalf("RequestPermissionDecision(context.Background(), ) error = %v", err)
+	}
+	if !granted {
+		t.Error("RequestPermissionDecision(context.Background(), ) = false, want true")
+	}
+
+	if captured.URL.String() != testTokenURL {
+		t.Errorf("URL = %v, want %v", captured.URL.String(), testTokenURL)
+	}
+	if got := captured.Header.Get("Authorization"); got != "Bearer access-token" {
+		t.Errorf("Authorization = %v, want Bearer access-token", got)
+	}
+
+	wantForm := map[string]string{
+		"grant_type":    umaTicketGrantType,
+		"audience":      "events-api",
+		"permission":    "events#read",
+		"response_mode": "decision",
+	}
+	for field, want := range wantForm {
+		if got := captured.PostForm.Get(field); got != want {
+			t.Errorf("form %s = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestRequestPermissionDecision_Responses(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantGranted bool
+		wantErr     bool
+	}{
+		{name: "Granted", status: http.StatusOK, body: `{"result":true}`, wantGranted: true},
+		{name: "Result false", status: http.StatusOK, body: `{"result":false}`},
+		{name: "Access denied", status: http.StatusForbidden, body: `{"error":"access_denied"}`},
+		{name: "Server error", status: http.StatusInternalServerError, wantErr: true},
+		{name: "Malformed body", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := decisionClient(&calls, tt.status, tt.body)
+
+			granted, err := RequestPermissionDecision(context.Background(), testTokenURL, "token", "events", "read", umaTestConfig(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RequestPermissionDecision(context.Background(), ) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if granted != tt.wantGranted {
+				t.Errorf("RequestPermissionDecision(context.Background(), ) = %v, want %v", granted, tt.wantGranted)
+			}
+		})
+	}
+}
+
+func TestPermissionEvaluator_CachesDecisionsPerScope(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	evaluator := NewPermissionEvaluator(umaTestConfig(), decisionClient(&calls, http.StatusOK, `{"result":true}`))
+	evaluator.cache.now = clock.now
+	claims := &AuthClaims{Subject: "user-123", ExpiresAt: clock.current.Add(time.Hour)}
+
+	for range 3 {
+		granted, err := evaluator.HasPermission(context.Background(), "token", claims, "events", "read")
+		if err != nil || !granted {
+			t.Fatalf("HasPermission() = %v, %v; want true, nil", granted, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("decision calls = %d, want 1", calls)
+	}
+
+	// A different scope needs its own decision
+	evaluator.HasPermission(context.Background(), "token", claims, "events", "write")
+	if calls != 2 {
+		t.Errorf("decision calls for new scope = %d, want 2", calls)
+	}
+
+	clock.advance(time.Minute)
+	evaluator.HasPermission(context.Background(), "token", claims, "events", "read")
+	if calls != 3 {
+		t.Errorf("decision calls after TTL = %d, want 3", calls)
+	}
+}
+
+func TestPermissionEvaluator_CachesDenials(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	calls := 0
+	evaluator := NewPermissionEvaluator(umaTestConfig(), decisionClient(&calls, http.StatusForbidden, `{}`))
+	evaluator.cache.now = clock.now
+	claims := &AuthClaims{Subject: "user-123", ExpiresAt: clock.current.Add(10 * time.Second)}
+
+	for range 2 {
+		granted, err := evaluator.HasPermission(context.Background(), "token", claims, "events", "delete")
+		if err != nil || granted {
+			t.Fatalf("HasPermission() = %v, %v; want false, nil", granted, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("decision calls = %d, want 1", calls)
+	}
+
+	// The decision never outlives the token
+	clock.advance(10 * time.Second)
+	evaluator.HasPermission(context.Background(), "token", claims, "events", "delete")
+	if calls != 2 {
+		t.Errorf("decision calls after token expiry = %d, want 2", calls)
+	}
+}
+
+func TestPermissionEvaluator_DoesNotCacheErrors(t *testing.T) {
+	calls := 0
+	client := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("connection refused")
+		},
+	}
+	evaluator := NewPermissionEvaluator(umaTestConfig(), client)
+
+	for range 2 {
+		if _, err := evaluator.HasPermission(context.Background(), "token", &AuthClaims{}, "events", "read"); err == nil {
+			t.Fatal("HasPermission() expected error")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("decision calls = %d, want 2", calls)
+	}
+}
@@ -15,11 +15,22 @@ const (
 	ValidationMethodIntrospection ValidationMethod = "introspection"
 	// ValidationMethodJWKS validates tokens locally using JWKS
 	ValidationMethodJWKS ValidationMethod = "jwks"
+	// ValidationMethodHybrid validates tokens locally using JWKS and checks for
+	// revocation via introspection periodically or on demand
+	ValidationMethodHybrid ValidationMethod = "hybrid"
 )
 
 // TokenValidator is the interface for validating OAuth tokens
+// ctx is the request context; validators making network calls abort when it is done
 type TokenValidator interface {
-	ValidateToken(token string) (*AuthClaims, error)
+	ValidateToken(ctx context.Context, token string) (*AuthClaims, error)
+}
+
+// StrictTokenValidator is implemented by validators that can perform an additional,
+// revocation-aware check on demand, e.g. for write requests or sensitive routes
+type StrictTokenValidator interface {
+	TokenValidator
+	ValidateTokenStrict(ctx context.Context, token string) (*AuthClaims, error)
 }
 
 // ValidatorConfig holds configuration for creating a TokenValidator
@@ -30,15 +41,31 @@ type ValidatorConfig struct {
 }
 
 // NewTokenValidator creates a TokenValidator based on the specified method
+// If AuthConfig.TrustedIssuers is set, a MultiIssuerValidator is created instead,
+// using method for the issuers that do not specify their own
 func NewTokenValidator(method ValidationMethod, cfg ValidatorConfig) (TokenValidator, error) {
+	if len(cfg.AuthConfig.TrustedIssuers) > 0 {
+		if cfg.Context == nil {
+			cfg.Context = context.Background()
+		}
+		authConfig := cfg.AuthConfig
+		authConfig.ValidationMethod = string(method)
+		return NewMultiIssuerValidatorFromConfig(cfg.Context, authConfig, cfg.HTTPClient)
+	}
+
 	switch method {
 	case ValidationMethodIntrospection, "":
-		return NewIntrospectionValidator(cfg.AuthConfig, cfg.HTTPClient), nil
+		return NewIntrospectionValidator(cfg.AuthConfig, cfg.HTTPClient)
 	case ValidationMethodJWKS:
 		if cfg.Context == nil {
 			cfg.Context = context.Background()
 		}
-		return NewJWKSValidatorFromConfig(cfg.Context, cfg.AuthConfig)
+		return NewJWKSValidatorFromConfig(cfg.Context, cfg.AuthConfig, cfg.HTTPClient)
+	case ValidationMethodHybrid:
+		if cfg.Context == nil {
+			cfg.Context = context.Background()
+		}
+		return NewHybridValidatorFromConfig(cfg.Context, cfg.AuthConfig, cfg.HTTPClient)
 	default:
 		return nil, fmt.Errorf("unsupported validation method: %s", method)
 	}
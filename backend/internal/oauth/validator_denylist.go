@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// denylistLookupTimeout bounds a single denylist lookup
+const denylistLookupTimeout = 2 * time.Second
+
+// TokenDenylist reports whether a token has been revoked individually by its jti claim
+// repository.TokenRevocationRepository satisfies this interface
+type TokenDenylist interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// DenylistValidator rejects tokens whose jti is on the denylist after the wrapped
+// validator accepted them
+// Tokens without a jti cannot be denylisted and are passed through
+// Lookup failures reject the token
+type DenylistValidator struct {
+	next     TokenValidator
+	denylist TokenDenylist
+}
+
+// NewDenylistValidator wraps a TokenValidator with denylist checks
+func NewDenylistValidator(next TokenValidator, denylist TokenDenylist) *DenylistValidator {
+	return &DenylistValidator{next: next, denylist: denylist}
+}
+
+// ValidateToken validates the token and checks it is not on the denylist
+func (v *DenylistValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	claims, err := v.next.ValidateToken(ctx, token)
+	return v.check(ctx, claims, err)
+}
+
+// ValidateTokenStrict validates the token strictly if the wrapped validator supports it
+// and checks it is not on the denylist
+func (v *DenylistValidator) ValidateTokenStrict(ctx context.Context, token string) (*AuthClaims, error) {
+	if strict, ok := v.next.(StrictTokenValidator); ok {
+		claims, err := strict.ValidateTokenStrict(ctx, token)
+		return v.check(ctx, claims, err)
+	}
+	return v.ValidateToken(ctx, token)
+}
+
+// check rejects validated claims whose jti is denylisted
+func (v *DenylistValidator) check(ctx context.Context, claims *AuthClaims, err error) (*AuthClaims, error) {
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenID == "" {
+		return claims, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, denylistLookupTimeout)
+	defer cancel()
+
+	revoked, err := v.denylist.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, fmt.Errorf("denylist lookup failed: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
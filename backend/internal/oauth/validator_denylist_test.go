@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubDenylist is a TokenDenylist backed by a set of revoked jti values
+type stubDenylist struct {
+	revoked map[string]bool
+	err     error
+	calls   int
+}
+
+func (s *stubDenylist) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.calls++
+	return s.revoked[jti], s.err
+}
+
+func TestDenylistValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		claims    *AuthClaims
+		denylist  *stubDenylist
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "Not revoked",
+			claims:    &AuthClaims{Subject: "user-123", TokenID: "jti-1"},
+			denylist:  &stubDenylist{revoked: map[string]bool{"jti-2": true}},
+			wantCalls: 1,
+		},
+		{
+			name:      "Revoked",
+			claims:    &AuthClaims{Subject: "user-123", TokenID: "jti-2"},
+			denylist:  &stubDenylist{revoked: map[string]bool{"jti-2": true}},
+			wantErr:   ErrTokenRevoked,
+			wantCalls: 1,
+		},
+		{
+			name:      "Lookup fails",
+			claims:    &AuthClaims{Subject: "user-123", TokenID: "jti-1"},
+			denylist:  &stubDenylist{err: errors.New("database unavailable")},
+			wantErr:   errors.New("denylist lookup failed"),
+			wantCalls: 1,
+		},
+		{
+			name:     "Token without jti",
+			claims:   &AuthClaims{Subject: "user-123"},
+			denylist: &stubDenylist{err: errors.New("must not be called")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewDenylistValidator(&stubValidator{claims: tt.claims}, tt.denylist)
+
+			claims, err := validator.ValidateToken(context.Background(), "token")
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(tt.wantErr, ErrTokenRevoked) && !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("ValidateToken() error = %v, want ErrTokenRevoked", err)
+			}
+			if err == nil && claims.Subject != "user-123" {
+				t.Errorf("Subject = %v, want user-123", claims.Subject)
+			}
+			if tt.denylist.calls != tt.wantCalls {
+				t.Errorf("denylist calls = %d, want %d", tt.denylist.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDenylistValidator_PropagatesValidationError(t *testing.T) {
+	denylist := &stubDenylist{}
+	validator := NewDenylistValidator(&stubValidator{err: ErrTokenInactive}, denylist)
+
+	if _, err := validator.ValidateTokenStrict(context.Background(), "token"); !errors.Is(err, ErrTokenInactive) {
+		t.Errorf("ValidateTokenStrict() error = %v, want ErrTokenInactive", err)
+	}
+	if denylist.calls != 0 {
+		t.Errorf("denylist calls = %d, want 0", denylist.calls)
+	}
+}
@@ -0,0 +1,122 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// defaultHybridTrackedTokens bounds how many tokens the hybrid validator remembers
+// as recently introspected when no introspection cache size is configured
+const defaultHybridTrackedTokens = 1000
+
+// HybridValidator verifies JWT signatures locally and consults introspection
+// only when needed to detect revoked tokens:
+//   - the first time a token is seen and again once the recheck interval has elapsed,
+//     so a revoked token is rejected at most one interval after revocation
+//   - on a random sample of requests, if a sample rate is configured
+//   - always, when ValidateTokenStrict is used (write methods, sensitive routes)
+type HybridValidator struct {
+	local  TokenValidator
+	remote TokenValidator
+
+	recheckInterval time.Duration
+	sampleRate      float64
+	checked         *tokenCache // tokens confirmed active by introspection
+	random          func() float64
+}
+
+// NewHybridValidator creates a HybridValidator from a local and a remote validator
+// maxTracked bounds the number of tokens remembered as recently introspected
+func NewHybridValidator(local, remote TokenValidator, recheckInterval time.Duration, sampleRate float64, maxTracked int) *HybridValidator {
+	if maxTracked <= 0 {
+		maxTracked = defaultHybridTrackedTokens
+	}
+	return &HybridValidator{
+		local:           local,
+		remote:          remote,
+		recheckInterval: recheckInterval,
+		sampleRate:      sampleRate,
+		checked:         newTokenCache(maxTracked),
+		random:          rand.Float64,
+	}
+}
+
+// NewHybridValidatorFromConfig creates a HybridValidator using JWKS for local verification
+// and an uncached IntrospectionValidator for revocation checks
+func NewHybridValidatorFromConfig(ctx context.Context, authConfig config.AuthConfig, client HTTPClient) (*HybridValidator, error) {
+	local, err := NewJWKSValidatorFromConfig(ctx, authConfig, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// The introspection cache would widen the revocation window, so the
+	// hybrid validator tracks recently checked tokens itself instead
+	remoteConfig := authConfig
+	remoteConfig.IntrospectionCacheSize = 0
+	remote, err := NewIntrospectionValidator(remoteConfig, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHybridValidator(local, remote,
+		authConfig.HybridRecheckInterval,
+		authConfig.HybridSampleRate,
+		authConfig.IntrospectionCacheSize,
+	), nil
+}
+
+// ValidateToken verifies the token locally and introspects it when a periodic
+// or sampled revocation check is due
+func (v *HybridValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	claims, err := v.local.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	key := hashToken(token)
+	if _, ok := v.checked.get(key); ok && v.random() >= v.sampleRate {
+		return claims, nil
+	}
+
+	if err := v.checkRevocation(ctx, key, token, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ValidateTokenStrict verifies the token locally and always confirms via introspection
+// that it has not been revoked
+func (v *HybridValidator) ValidateTokenStrict(ctx context.Context, token string) (*AuthClaims, error) {
+	claims, err := v.local.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.checkRevocation(ctx, hashToken(token), token, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkRevocation introspects the token and remembers a positive result until the
+// next recheck is due; introspection failures reject the token
+func (v *HybridValidator) checkRevocation(ctx context.Context, key, token string, claims *AuthClaims) error {
+	remoteClaims, err := v.remote.ValidateToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("revocation check failed: %w", err)
+	}
+	if remoteClaims.Subject != claims.Subject {
+		return fmt.Errorf("revocation check failed: subject mismatch")
+	}
+
+	expiresAt := v.checked.now().Add(v.recheckInterval)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+	v.checked.set(key, nil, nil, expiresAt)
+	return nil
+}
@@ -0,0 +1,175 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// stubValidator is a TokenValidator returning fixed results and counting calls
+type stubValidator struct {
+	claims *AuthClaims
+	err    error
+	calls  int
+}
+
+func (s *stubValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	claims := *s.claims
+	return &claims, nil
+}
+
+// newTestHybridValidator creates a HybridValidator with stub validators and a fake clock
+func newTestHybridValidator(clock *fakeClock, sampleRate float64) (*HybridValidator, *stubValidator, *stubValidator) {
+	claims := &AuthClaims{Subject: "user-123", ExpiresAt: clock.current.Add(time.Hour)}
+	local := &stubValidator{claims: claims}
+	remote := &stubValidator{claims: claims}
+	validator := NewHybridValidator(local, remote, 30*time.Second, sampleRate, 10)
+	validator.checked.now = clock.now
+	return validator, local, remote
+}
+
+func TestHybridValidator_IntrospectsOncePerInterval(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, local, remote := newTestHybridValidator(clock, 0)
+
+	for range 3 {
+		if _, err := validator.ValidateToken(context.Background(), "token"); err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+	}
+	if local.calls != 3 {
+		t.Errorf("local calls = %d, want 3", local.calls)
+	}
+	if remote.calls != 1 {
+		t.Errorf("remote calls = %d, want 1", remote.calls)
+	}
+
+	clock.advance(30 * time.Second)
+	validator.ValidateToken(context.Background(), "token")
+	if remote.calls != 2 {
+		t.Errorf("remote calls after interval = %d, want 2", remote.calls)
+	}
+}
+
+func TestHybridValidator_RevokedTokenRejectedWithinInterval(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, _, remote := newTestHybridValidator(clock, 0)
+
+	if _, err := validator.ValidateToken(context.Background(), "token"); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	// Token is revoked in Keycloak; it is still accepted until the recheck is due
+	remote.err = ErrTokenInactive
+	clock.advance(10 * time.Second)
+	if _, err := validator.ValidateToken(context.Background(), "token"); err != nil {
+		t.Fatalf("ValidateToken() inside interval error = %v, want nil", err)
+	}
+
+	clock.advance(20 * time.Second)
+	_, err := validator.ValidateToken(context.Background(), "token")
+	if !errors.Is(err, ErrTokenInactive) {
+		t.Fatalf("ValidateToken() after interval error = %v, want ErrTokenInactive", err)
+	}
+}
+
+func TestHybridValidator_StrictAlwaysIntrospects(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, _, remote := newTestHybridValidator(clock, 0)
+
+	validator.ValidateToken(context.Background(), "token")
+	validator.ValidateTokenStrict(context.Background(), "token")
+	validator.ValidateTokenStrict(context.Background(), "token")
+
+	if remote.calls != 3 {
+		t.Errorf("remote calls = %d, want 3", remote.calls)
+	}
+
+	remote.err = ErrTokenInactive
+	if _, err := validator.ValidateTokenStrict(context.Background(), "token"); !errors.Is(err, ErrTokenInactive) {
+		t.Errorf("ValidateTokenStrict() error = %v, want ErrTokenInactive", err)
+	}
+}
+
+func TestHybridValidator_SampledRecheck(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, _, remote := newTestHybridValidator(clock, 0.5)
+
+	validator.ValidateToken(context.Background(), "token")
+
+	validator.random = func() float64 { return 0.9 }
+	validator.ValidateToken(context.Background(), "token")
+	if remote.calls != 1 {
+		t.Errorf("remote calls = %d, want 1 (not sampled)", remote.calls)
+	}
+
+	validator.random = func() float64 { return 0.1 }
+	validator.ValidateToken(context.Background(), "token")
+	if remote.calls != 2 {
+		t.Errorf("remote calls = %d, want 2 (sampled)", remote.calls)
+	}
+}
+
+func TestHybridValidator_LocalFailureSkipsIntrospection(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, local, remote := newTestHybridValidator(clock, 0)
+	local.err = errors.New("bad signature")
+
+	if _, err := validator.ValidateToken(context.Background(), "token"); err == nil {
+		t.Fatal("ValidateToken() expected error, got nil")
+	}
+	if remote.calls != 0 {
+		t.Errorf("remote calls = %d, want 0", remote.calls)
+	}
+}
+
+func TestHybridValidator_SubjectMismatch(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	validator, _, remote := newTestHybridValidator(clock, 0)
+	remote.claims = &AuthClaims{Subject: "someone-else"}
+
+	if _, err := validator.ValidateToken(context.Background(), "token"); err == nil {
+		t.Fatal("ValidateToken() expected error for subject mismatch, got nil")
+	}
+}
+
+func TestNewTokenValidator_Hybrid(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+	server := createMockJWKSServer(t, keyPair)
+	defer server.Close()
+
+	cfg := ValidatorConfig{
+		AuthConfig: config.AuthConfig{
+			KeycloakURL:            server.URL,
+			RealmName:              "test-realm",
+			IntrospectionCacheSize: 100,
+			IntrospectionCacheTTL:  time.Minute,
+			HybridRecheckInterval:  time.Minute,
+		},
+		Context: context.Background(),
+	}
+
+	validator, err := NewTokenValidator(ValidationMethodHybrid, cfg)
+	if err != nil {
+		t.Fatalf("NewTokenValidator() error = %v", err)
+	}
+
+	hybrid, ok := validator.(*HybridValidator)
+	if !ok {
+		t.Fatalf("Expected HybridValidator, got %T", validator)
+	}
+	remote, ok := hybrid.remote.(*IntrospectionValidator)
+	if !ok {
+		t.Fatalf("Expected remote IntrospectionValidator, got %T", hybrid.remote)
+	}
+	if remote.cache != nil {
+		t.Error("hybrid introspection must not use the introspection cache")
+	}
+}
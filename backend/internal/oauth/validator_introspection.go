@@ -1,29 +1,113 @@
 package oauth
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
 )
 
-// IntrospectionValidator validates tokens using Keycloak's introspection endpoint
+// IntrospectionValidator validates tokens using the identity provider's introspection endpoint
+// Concurrent validations of the same token share a single introspection request;
+// when AuthConfig.IntrospectionCacheSize is positive, results are also cached per token
 type IntrospectionValidator struct {
-	authConfig config.AuthConfig
-	client     HTTPClient
+	authConfig    config.AuthConfig
+	client        HTTPClient
+	endpoints     EndpointResolver
+	mapping       ClaimMapping
+	authenticator ClientAuthenticator
+	inflight      inflightGroup
+
+	cache       *tokenCache // nil when caching is disabled
+	ttl         time.Duration
+	negativeTTL time.Duration
 }
 
 // NewIntrospectionValidator creates a new IntrospectionValidator
-func NewIntrospectionValidator(authConfig config.AuthConfig, client HTTPClient) *IntrospectionValidator {
+// It fails if the claim mapping or client authentication configured in AuthConfig is invalid
+func NewIntrospectionValidator(authConfig config.AuthConfig, client HTTPClient) (*IntrospectionValidator, error) {
 	if client == nil {
-		client = &http.Client{}
+		client = defaultHTTPClient()
+	}
+	mapping, err := NewClaimMapping(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := NewClientAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
 	}
-	return &IntrospectionValidator{
-		authConfig: authConfig,
-		client:     client,
+	v := &IntrospectionValidator{
+		authConfig:    authConfig,
+		client:        client,
+		endpoints:     NewEndpointResolver(authConfig, client),
+		mapping:       mapping,
+		authenticator: authenticator,
+		ttl:           authConfig.IntrospectionCacheTTL,
+		negativeTTL:   authConfig.IntrospectionCacheNegativeTTL,
 	}
+	if authConfig.IntrospectionCacheSize > 0 && authConfig.IntrospectionCacheTTL > 0 {
+		v.cache = newTokenCache(authConfig.IntrospectionCacheSize)
+	}
+	return v, nil
 }
 
 // ValidateToken validates the token via introspection and returns AuthClaims
-func (v *IntrospectionValidator) ValidateToken(token string) (*AuthClaims, error) {
-	return IntrospectToken(token, v.authConfig, v.client)
+// Concurrent callers share one request, which is therefore not canceled with ctx;
+// each caller still stops waiting once its own ctx is done
+func (v *IntrospectionValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	key := hashToken(token)
+	if v.cache != nil {
+		if cached, ok := v.cache.get(key); ok {
+			return cached.claims, cached.err
+		}
+	}
+
+	return v.inflight.do(ctx, key, func() (*AuthClaims, error) {
+		claims, err := v.introspect(context.WithoutCancel(ctx), token)
+		if v.cache != nil {
+			v.storeResult(key, claims, err)
+		}
+		return claims, err
+	})
+}
+
+// introspect calls the introspection endpoint currently advertised by the resolver
+func (v *IntrospectionValidator) introspect(ctx context.Context, token string) (*AuthClaims, error) {
+	endpoints, err := v.endpoints.Endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve introspection endpoint: %w", err)
+	}
+	if endpoints.IntrospectionURL == "" {
+		return nil, errors.New("identity provider has no introspection endpoint")
+	}
+	return introspectTokenAt(ctx, endpoints.IntrospectionURL, token, v.authConfig, v.client, v.mapping, v.authenticator)
+}
+
+// CoalescedCalls returns how many validations were served by an introspection
+// request that was already in flight for the same token
+func (v *IntrospectionValidator) CoalescedCalls() uint64 {
+	return v.inflight.coalescedCount()
+}
+
+// storeResult caches an introspection result
+// Active results live for at most the configured TTL and never past the token's exp;
+// inactive results use the separate negative TTL; transport errors are never cached
+func (v *IntrospectionValidator) storeResult(key string, claims *AuthClaims, err error) {
+	now := v.cache.now()
+
+	if err != nil {
+		if errors.Is(err, ErrTokenInactive) && v.negativeTTL > 0 {
+			v.cache.set(key, nil, err, now.Add(v.negativeTTL))
+		}
+		return
+	}
+
+	expiresAt := now.Add(v.ttl)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+	v.cache.set(key, claims, nil, expiresAt)
 }
@@ -0,0 +1,150 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+// ErrUntrustedIssuer is returned when a token's iss claim is not in the trusted issuers list
+var ErrUntrustedIssuer = errors.New("token issuer not trusted")
+
+// MultiIssuerValidator routes each token to the validator of its issuer
+// The iss claim is read from the unverified token only to pick the validator;
+// the chosen validator then verifies the token and its issuer as usual
+// Encrypted tokens are decrypted to read the issuer; the validator receives the original token
+type MultiIssuerValidator struct {
+	validators map[string]TokenValidator // issuer -> validator
+	decrypter  *TokenDecrypter           // nil rejects encrypted tokens
+}
+
+// NewMultiIssuerValidator creates a MultiIssuerValidator from validators keyed by issuer
+func NewMultiIssuerValidator(validators map[string]TokenValidator) *MultiIssuerValidator {
+	return &MultiIssuerValidator{validators: validators}
+}
+
+// NewMultiIssuerValidatorFromConfig creates a validator per entry of AuthConfig.TrustedIssuers
+// Entries have the form "<issuer>" or "<issuer>|<method>"; without a method, AuthConfig.ValidationMethod is used
+func NewMultiIssuerValidatorFromConfig(ctx context.Context, authConfig config.AuthConfig, client HTTPClient) (*MultiIssuerValidator, error) {
+	validators := make(map[string]TokenValidator, len(authConfig.TrustedIssuers))
+	for _, entry := range authConfig.TrustedIssuers {
+		issuer, method := ParseTrustedIssuer(entry, ValidationMethod(authConfig.ValidationMethod))
+		if _, exists := validators[issuer]; exists {
+			return nil, fmt.Errorf("duplicate trusted issuer: %s", issuer)
+		}
+
+		validator, err := NewTokenValidator(method, ValidatorConfig{
+			AuthConfig: issuerAuthConfig(authConfig, issuer),
+			HTTPClient: client,
+			Context:    ctx,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create validator for issuer %s: %w", issuer, err)
+		}
+		validators[issuer] = validator
+	}
+
+	v := NewMultiIssuerValidator(validators)
+	if authConfig.TokenDecryptionKeysFile != "" {
+		decrypter, err := LoadTokenDecrypter(authConfig.TokenDecryptionKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		v.decrypter = decrypter
+	}
+	return v, nil
+}
+
+// ParseTrustedIssuer splits a trusted issuer entry into issuer and validation method
+func ParseTrustedIssuer(entry string, defaultMethod ValidationMethod) (string, ValidationMethod) {
+	issuer, method, ok := strings.Cut(entry, "|")
+	if !ok || method == "" {
+		return strings.TrimSpace(issuer), defaultMethod
+	}
+	return strings.TrimSpace(issuer), ValidationMethod(strings.TrimSpace(method))
+}
+
+// issuerAuthConfig derives the AuthConfig for a single trusted issuer
+// Keycloak realms are reached via KeycloakURL, so the backend can use an internal
+// hostname; other issuers are configured via their discovery document
+func issuerAuthConfig(authConfig config.AuthConfig, issuer string) config.AuthConfig {
+	issuerConfig := authConfig
+	issuerConfig.TrustedIssuers = nil
+	issuerConfig.ExpectedIssuer = issuer
+	if realm := RealmFromIssuer(issuer); realm != "" {
+		issuerConfig.RealmName = realm
+		issuerConfig.DiscoveryURL = ""
+	} else {
+		issuerConfig.DiscoveryURL = strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	}
+	return issuerConfig
+}
+
+// ValidateToken validates the token with the validator of its issuer
+func (v *MultiIssuerValidator) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	issuer, validator, err := v.route(token)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := validator.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return withIssuer(claims, issuer)
+}
+
+// ValidateTokenStrict validates the token strictly if the issuer's validator supports it
+func (v *MultiIssuerValidator) ValidateTokenStrict(ctx context.Context, token string) (*AuthClaims, error) {
+	issuer, validator, err := v.route(token)
+	if err != nil {
+		return nil, err
+	}
+	var claims *AuthClaims
+	if strict, ok := validator.(StrictTokenValidator); ok {
+		claims, err = strict.ValidateTokenStrict(ctx, token)
+	} else {
+		claims, err = validator.ValidateToken(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withIssuer(claims, issuer)
+}
+
+// route selects the validator for the token's unverified iss claim
+func (v *MultiIssuerValidator) route(token string) (string, TokenValidator, error) {
+	if IsEncryptedToken(token) {
+		if v.decrypter == nil {
+			return "", nil, fmt.Errorf("%w: no decryption keys configured", ErrTokenDecryption)
+		}
+		nested, err := v.decrypter.Decrypt(token)
+		if err != nil {
+			return "", nil, err
+		}
+		token = nested
+	}
+
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &unverified); err != nil {
+		return "", nil, fmt.Errorf("failed to read token issuer: %w", err)
+	}
+	validator, ok := v.validators[unverified.Issuer]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrUntrustedIssuer, unverified.Issuer)
+	}
+	return unverified.Issuer, validator, nil
+}
+
+// withIssuer records the issuer in the claims, rejecting claims verified for a different issuer
+func withIssuer(claims *AuthClaims, issuer string) (*AuthClaims, error) {
+	if claims.Issuer != "" && claims.Issuer != issuer {
+		return nil, fmt.Errorf("%w: verified issuer %q does not match %q", ErrUntrustedIssuer, claims.Issuer, issuer)
+	}
+	claims.Issuer = issuer
+	claims.Realm = RealmFromIssuer(issuer)
+	return claims, nil
+}
@@ -0,0 +1,215 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
+)
+
+const (
+	testIssuerEvents = "http://localhost:8081/realms/events"
+	testIssuerSports = "http://localhost:8081/realms/sports"
+)
+
+// unsignedIssuerToken creates a JWT carrying only the given iss claim
+// The signature is irrelevant for routing, which reads the claim unverified
+func unsignedIssuerToken(t *testing.T, issuer string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": issuer}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return token
+}
+
+func TestMultiIssuerValidator_RoutesByIssuer(t *testing.T) {
+	events := &stubValidator{claims: &AuthClaims{Subject: "events-user"}}
+	sports := &stubValidator{claims: &AuthClaims{Subject: "sports-user"}}
+	validator := NewMultiIssuerValidator(map[string]TokenValidator{
+		testIssuerEvents: events,
+		testIssuerSports: sports,
+	})
+
+	claims, err := validator.ValidateToken(context.Background(), unsignedIssuerToken(t, testIssuerSports))
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Subject != "sports-user" {
+		t.Errorf("Subject = %v, want sports-user", claims.Subject)
+	}
+	if claims.Issuer != testIssuerSports {
+		t.Errorf("Issuer = %v, want %v", claims.Issuer, testIssuerSports)
+	}
+	if claims.Realm != "sports" {
+		t.Errorf("Realm = %v, want sports", claims.Realm)
+	}
+	if events.calls != 0 || sports.calls != 1 {
+		t.Errorf("calls = events %d, sports %d; want 0, 1", events.calls, sports.calls)
+	}
+}
+
+func TestMultiIssuerValidator_Rejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		validator *stubValidator
+		wantErr   error
+	}{
+		{
+			name:      "Untrusted issuer",
+			token:     unsignedIssuerToken(t, "https://evil.example.com/realms/events"),
+			validator: &stubValidator{claims: &AuthClaims{Subject: "user-123"}},
+			wantErr:   ErrUntrustedIssuer,
+		},
+		{
+			name:      "Verified issuer differs",
+			token:     unsignedIssuerToken(t, testIssuerEvents),
+			validator: &stubValidator{claims: &AuthClaims{Subject: "user-123", Issuer: testIssuerSports}},
+			wantErr:   ErrUntrustedIssuer,
+		},
+		{
+			name:      "Issuer validator fails",
+			token:     unsignedIssuerToken(t, testIssuerEvents),
+			validator: &stubValidator{err: ErrTokenInactive},
+			wantErr:   ErrTokenInactive,
+		},
+		{
+			name:      "Not a JWT",
+			token:     "opaque-token",
+			validator: &stubValidator{claims: &AuthClaims{Subject: "user-123"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewMultiIssuerValidator(map[string]TokenValidator{testIssuerEvents: tt.validator})
+
+			_, err := validator.ValidateToken(context.Background(), tt.token)
+			if err == nil {
+				t.Fatal("ValidateToken() expected error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMultiIssuerValidator_StrictDelegation(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	hybrid, _, remote := newTestHybridValidator(clock, 0)
+	validator := NewMultiIssuerValidator(map[string]TokenValidator{testIssuerEvents: hybrid})
+	token := unsignedIssuerToken(t, testIssuerEvents)
+
+	for range 2 {
+		if _, err := validator.ValidateTokenStrict(context.Background(), token); err != nil {
+			t.Fatalf("ValidateTokenStrict() error = %v", err)
+		}
+	}
+	if remote.calls != 2 {
+		t.Errorf("remote calls = %d, want 2", remote.calls)
+	}
+}
+
+func TestParseTrustedIssuer(t *testing.T) {
+	tests := []struct {
+		entry      string
+		wantIssuer string
+		wantMethod ValidationMethod
+	}{
+		{entry: testIssuerEvents, wantIssuer: testIssuerEvents, wantMethod: ValidationMethodIntrospection},
+		{entry: testIssuerEvents + "|jwks", wantIssuer: testIssuerEvents, wantMethod: ValidationMethodJWKS},
+		{entry: " " + testIssuerEvents + " | hybrid ", wantIssuer: testIssuerEvents, wantMethod: ValidationMethodHybrid},
+		{entry: testIssuerEvents + "|", wantIssuer: testIssuerEvents, wantMethod: ValidationMethodIntrospection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			issuer, method := ParseTrustedIssuer(tt.entry, ValidationMethodIntrospection)
+			if issuer != tt.wantIssuer || method != tt.wantMethod {
+				t.Errorf("ParseTrustedIssuer() = %v, %v; want %v, %v", issuer, method, tt.wantIssuer, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestRealmFromIssuer(t *testing.T) {
+	tests := []struct {
+		issuer string
+		want   string
+	}{
+		{issuer: "http://localhost:8081/realms/events", want: "events"},
+		{issuer: "https://auth.example.com/auth/realms/sports", want: "sports"},
+		{issuer: "https://accounts.example.com", want: ""},
+		{issuer: "https://auth.example.com/realms/", want: ""},
+		{issuer: "https://auth.example.com/realms/events/extra", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.issuer, func(t *testing.T) {
+			if got := RealmFromIssuer(tt.issuer); got != tt.want {
+				t.Errorf("RealmFromIssuer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMultiIssuerValidatorFromConfig(t *testing.T) {
+	keyPair := generateTestKeyPair(t)
+
+	// Both realms are served by the same Keycloak, reached via KeycloakURL
+	var requestedPaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPaths = append(requestedPaths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(keyPair.jwksResponse())
+	}))
+	defer server.Close()
+
+	validator, err := NewTokenValidator(ValidationMethodIntrospection, ValidatorConfig{
+		AuthConfig: config.AuthConfig{
+			KeycloakURL:    server.URL,
+			RealmName:      "ignored",
+			TrustedIssuers: []string{testIssuerEvents + "|jwks", testIssuerSports},
+		},
+		Context: context.Background(),
+	})
+	if err != nil {
+		t.Fatalf("NewTokenValidator() error = %v", err)
+	}
+
+	multi, ok := validator.(*MultiIssuerValidator)
+	if !ok {
+		t.Fatalf("NewTokenValidator() = %T, want *MultiIssuerValidator", validator)
+	}
+
+	jwksValidator, ok := multi.validators[testIssuerEvents].(*JWKSValidator)
+	if !ok {
+		t.Fatalf("events validator = %T, want *JWKSValidator", multi.validators[testIssuerEvents])
+	}
+	if jwksValidator.expectedIssuer != testIssuerEvents {
+		t.Errorf("expectedIssuer = %v, want %v", jwksValidator.expectedIssuer, testIssuerEvents)
+	}
+	if len(requestedPaths) == 0 || requestedPaths[0] != "/realms/events/protocol/openid-connect/certs" {
+		t.Errorf("JWKS requests = %v, want /realms/events/protocol/openid-connect/certs", requestedPaths)
+	}
+
+	if _, ok := multi.validators[testIssuerSports].(*IntrospectionValidator); !ok {
+		t.Errorf("sports validator = %T, want *IntrospectionValidator", multi.validators[testIssuerSports])
+	}
+}
+
+func TestNewMultiIssuerValidatorFromConfig_DuplicateIssuer(t *testing.T) {
+	_, err := NewMultiIssuerValidatorFromConfig(context.Background(), config.AuthConfig{
+		TrustedIssuers: []string{testIssuerEvents, testIssuerEvents + "|jwks"},
+	}, nil)
+	if err == nil {
+		t.Fatal("NewMultiIssuerValidatorFromConfig() expected error for duplicate issuer")
+	}
+}
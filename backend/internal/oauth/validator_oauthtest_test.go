@@ -0,0 +1,130 @@
+package oauth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/oauth/oauthtest"
+)
+
+// These tests run the validators against an in-process fake realm
+
+func TestValidators_AgainstFakeRealm(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+
+	token := realm.MintToken(oauthtest.TokenOptions{
+		Subject:     "user-123",
+		Username:    "alice",
+		Scopes:      []string{"events-api-access", "events:read"},
+		Roles:       []string{"system-admin"},
+		ClientRoles: map[string][]string{"events-api": {"org-maintainer"}},
+	})
+
+	for _, method := range []ValidationMethod{ValidationMethodIntrospection, ValidationMethodJWKS, ValidationMethodHybrid} {
+		t.Run(string(method), func(t *testing.T) {
+			validator, err := NewTokenValidator(method, ValidatorConfig{AuthConfig: realm.AuthConfig(), Context: context.Background()})
+			if err != nil {
+				t.Fatalf("NewTokenValidator() error = %v", err)
+			}
+
+			claims, err := validator.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.Subject != "user-123" || claims.Username != "alice" {
+				t.Errorf("Subject, Username = %q, %q, want user-123, alice", claims.Subject, claims.Username)
+			}
+			if claims.Issuer != realm.Issuer() || claims.Realm != "events" {
+				t.Errorf("Issuer, Realm = %q, %q, want %q, events", claims.Issuer, claims.Realm, realm.Issuer())
+			}
+			if !slices.Contains(claims.Scopes, "events:read") {
+				t.Errorf("Scopes = %v, want events:read", claims.Scopes)
+			}
+			if !slices.Contains(claims.Roles, "system-admin") || !slices.Contains(claims.Roles, "events-api:org-maintainer") {
+				t.Errorf("Roles = %v, want system-admin and events-api:org-maintainer", claims.Roles)
+			}
+
+			if _, err := validator.ValidateToken(context.Background(), "not-a-jwt"); err == nil {
+				t.Error("ValidateToken() of a malformed token error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestIntrospectionValidator_FakeRealmRevocation(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	validator := mustIntrospectionValidator(t, realm.AuthConfig(), nil)
+
+	token := realm.MintToken(oauthtest.TokenOptions{})
+	if _, err := validator.ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	realm.Revoke(token)
+	if _, err := validator.ValidateToken(context.Background(), token); err == nil {
+		t.Error("ValidateToken() of a revoked token error = nil, want error")
+	}
+}
+
+func TestJWKSValidator_FakeRealmKeyRotation(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	validator, err := NewJWKSValidatorFromConfig(context.Background(), realm.AuthConfig(), nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+	}
+
+	before := realm.MintToken(oauthtest.TokenOptions{})
+	if _, err := validator.ValidateToken(context.Background(), before); err != nil {
+		t.Fatalf("ValidateToken() before rotation error = %v", err)
+	}
+
+	realm.RotateKeys()
+	after := realm.MintToken(oauthtest.TokenOptions{})
+	if _, err := validator.ValidateToken(context.Background(), after); err != nil {
+		t.Errorf("ValidateToken() with the new key error = %v", err)
+	}
+	if _, err := validator.ValidateToken(context.Background(), before); err != nil {
+		t.Errorf("ValidateToken() with the previous key error = %v", err)
+	}
+}
+
+func TestJWKSValidator_FakeRealmExpiredToken(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+	validator, err := NewJWKSValidatorFromConfig(context.Background(), realm.AuthConfig(), nil)
+	if err != nil {
+		t.Fatalf("NewJWKSValidatorFromConfig() error = %v", err)
+	}
+
+	token := realm.MintToken(oauthtest.TokenOptions{ExpiresIn: -time.Minute})
+	if _, err := validator.ValidateToken(context.Background(), token); err == nil {
+		t.Error("ValidateToken() of an expired token error = nil, want error")
+	}
+}
+
+func TestClientCredentialsTokenSource_FakeRealm(t *testing.T) {
+	realm := oauthtest.NewServer("events")
+	defer realm.Close()
+
+	source := mustClientCredentialsTokenSource(t, realm.AuthConfig(), nil, "events:read")
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	claims, err := mustIntrospectionValidator(t, realm.AuthConfig(), nil).ValidateToken(context.Background(), token.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Subject != "service-account-"+oauthtest.DefaultClientID {
+		t.Errorf("Subject = %q, want service-account-%s", claims.Subject, oauthtest.DefaultClientID)
+	}
+	if !slices.Contains(claims.Scopes, "events:read") {
+		t.Errorf("Scopes = %v, want events:read", claims.Scopes)
+	}
+}
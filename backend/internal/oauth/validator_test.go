@@ -7,7 +7,10 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/config"
 )
@@ -22,6 +25,55 @@ func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return m.DoFunc(req)
 }
 
+// createMockResponse creates a mock HTTP response with the given status code and body
+func createMockResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+// countingClient counts requests in *calls and answers them with respond
+// Requests are handled one at a time and *calls is incremented before respond runs
+func countingClient(calls *int, respond func(req *http.Request) (*http.Response, error)) *MockHTTPClient {
+	var mu sync.Mutex
+	return &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			*calls++
+			return respond(req)
+		},
+	}
+}
+
+// mustIntrospectionValidator creates an IntrospectionValidator, failing the test on error
+func mustIntrospectionValidator(t *testing.T, authConfig config.AuthConfig, client HTTPClient) *IntrospectionValidator {
+	t.Helper()
+	validator, err := NewIntrospectionValidator(authConfig, client)
+	if err != nil {
+		t.Fatalf("NewIntrospectionValidator() error = %v", err)
+	}
+	return validator
+}
+
+func TestNewIntrospectionValidator_InvalidClaimMapping(t *testing.T) {
+	var calls int
+	client := &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("unexpected request")
+	}}
+
+	_, err := NewIntrospectionValidator(config.AuthConfig{ClaimRoles: []string{"realm_access"}}, client)
+	if err == nil {
+		t.Error("NewIntrospectionValidator() expected error for an invalid claim path")
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d, want 0", calls)
+	}
+}
+
 func TestNewTokenValidator_Introspection(t *testing.T) {
 	cfg := ValidatorConfig{
 		AuthConfig: config.AuthConfig{
@@ -99,11 +151,7 @@ func TestIntrospectionValidator_ValidToken(t *testing.T) {
 				Username: "testuser",
 			}
 			responseBody, _ := json.Marshal(response)
-			return &http.Response{
-				StatusCode: http.StatusOK,
-				Body:       io.NopCloser(bytes.NewBufferString(string(responseBody))),
-				Header:     make(http.Header),
-			}, nil
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
 		},
 	}
 
@@ -114,9 +162,9 @@ func TestIntrospectionValidator_ValidToken(t *testing.T) {
 		RealmName:    "test-realm",
 	}
 
-	validator := NewIntrospectionValidator(authConfig, mockClient)
+	validator := mustIntrospectionValidator(t, authConfig, mockClient)
 
-	claims, err := validator.ValidateToken("valid-token")
+	claims, err := validator.ValidateToken(context.Background(), "valid-token")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -135,6 +183,42 @@ func TestIntrospectionValidator_ValidToken(t *testing.T) {
 	}
 }
 
+func TestIntrospectionValidator_KeycloakRolesAndEmail(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			response := TokenIntrospectionResponse{
+				Active:      true,
+				Sub:         "user-123",
+				Username:    "testuser",
+				Email:       "test@example.com",
+				RealmAccess: KeycloakRoles{Roles: []string{"org-user"}},
+				ResourceAccess: map[string]KeycloakRoles{
+					"events-api": {Roles: []string{"org-maintainer"}},
+				},
+			}
+			responseBody, _ := json.Marshal(response)
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
+		},
+	}
+
+	validator := mustIntrospectionValidator(t, config.AuthConfig{}, mockClient)
+
+	claims, err := validator.ValidateToken(context.Background(), "valid-token")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if claims.Email != "test@example.com" {
+		t.Errorf("Expected Email 'test@example.com', got '%s'", claims.Email)
+	}
+	if !claims.HasRole("org-user") {
+		t.Error("Expected claims to have realm role 'org-user'")
+	}
+	if !claims.HasRole("events-api:org-maintainer") {
+		t.Error("Expected claims to have client role 'events-api:org-maintainer'")
+	}
+}
+
 func TestIntrospectionValidator_InvalidToken(t *testing.T) {
 	mockClient := &MockHTTPClient{
 		DoFunc: func(req *http.Request) (*http.Response, error) {
@@ -142,11 +226,7 @@ func TestIntrospectionValidator_InvalidToken(t *testing.T) {
 				Active: false,
 			}
 			responseBody, _ := json.Marshal(response)
-			return &http.Response{
-				StatusCode: http.StatusOK,
-				Body:       io.NopCloser(bytes.NewBufferString(string(responseBody))),
-				Header:     make(http.Header),
-			}, nil
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
 		},
 	}
 
@@ -157,9 +237,9 @@ func TestIntrospectionValidator_InvalidToken(t *testing.T) {
 		RealmName:    "test-realm",
 	}
 
-	validator := NewIntrospectionValidator(authConfig, mockClient)
+	validator := mustIntrospectionValidator(t, authConfig, mockClient)
 
-	_, err := validator.ValidateToken("invalid-token")
+	_, err := validator.ValidateToken(context.Background(), "invalid-token")
 	if err == nil {
 		t.Fatal("Expected error for inactive token")
 	}
@@ -179,9 +259,9 @@ func TestIntrospectionValidator_HTTPError(t *testing.T) {
 		RealmName:    "test-realm",
 	}
 
-	validator := NewIntrospectionValidator(authConfig, mockClient)
+	validator := mustIntrospectionValidator(t, authConfig, mockClient)
 
-	_, err := validator.ValidateToken("any-token")
+	_, err := validator.ValidateToken(context.Background(), "any-token")
 	if err == nil {
 		t.Fatal("Expected error for HTTP failure")
 	}
@@ -196,7 +276,7 @@ func TestIntrospectionValidator_NilClient(t *testing.T) {
 	}
 
 	// Should not panic with nil client
-	validator := NewIntrospectionValidator(authConfig, nil)
+	validator := mustIntrospectionValidator(t, authConfig, nil)
 	if validator == nil {
 		t.Fatal("Expected validator to be created")
 	}
@@ -212,6 +292,9 @@ func TestValidationMethod_Constants(t *testing.T) {
 	if ValidationMethodJWKS != "jwks" {
 		t.Errorf("Expected 'jwks', got '%s'", ValidationMethodJWKS)
 	}
+	if ValidationMethodHybrid != "hybrid" {
+		t.Errorf("Expected 'hybrid', got '%s'", ValidationMethodHybrid)
+	}
 }
 
 func TestNewTokenValidator_JWKS_RequiresContext(t *testing.T) {
@@ -283,11 +366,7 @@ func TestIntrospectionValidator_AlgNoneTokenRejectedByKeycloak(t *testing.T) {
 				Active: false,
 			}
 			responseBody, _ := json.Marshal(response)
-			return &http.Response{
-				StatusCode: http.StatusOK,
-				Body:       io.NopCloser(bytes.NewBufferString(string(responseBody))),
-				Header:     make(http.Header),
-			}, nil
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
 		},
 	}
 
@@ -298,13 +377,13 @@ func TestIntrospectionValidator_AlgNoneTokenRejectedByKeycloak(t *testing.T) {
 		RealmName:    "test-realm",
 	}
 
-	validator := NewIntrospectionValidator(authConfig, mockClient)
+	validator := mustIntrospectionValidator(t, authConfig, mockClient)
 
 	// Craft an "alg: none" attack token (same format as the JWKS test)
 	// This token has no signature, attempting to bypass validation
 	algNoneToken := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJhdHRhY2tlciJ9."
 
-	_, err := validator.ValidateToken(algNoneToken)
+	_, err := validator.ValidateToken(context.Background(), algNoneToken)
 
 	if err == nil {
 		t.Fatal("ValidateToken() expected error for 'alg: none' attack token, got nil")
@@ -313,3 +392,93 @@ func TestIntrospectionValidator_AlgNoneTokenRejectedByKeycloak(t *testing.T) {
 	// The error should indicate the token is not active
 	t.Logf("Token correctly rejected with error: %v", err)
 }
+
+func TestIntrospectionValidator_CoalescesConcurrentCalls(t *testing.T) {
+	const concurrency = 10
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			<-release
+			response := TokenIntrospectionResponse{
+				Active: true,
+				Scope:  "test-scope",
+				Sub:    "user-123",
+			}
+			responseBody, _ := json.Marshal(response)
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
+		},
+	}
+
+	validator := mustIntrospectionValidator(t, config.AuthConfig{
+		KeycloakURL: "http://keycloak:8080",
+		RealmName:   "test-realm",
+	}, mockClient)
+
+	var wg sync.WaitGroup
+	results := make(chan *AuthClaims, concurrency)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claims, err := validator.ValidateToken(context.Background(), "same-token")
+			if err != nil {
+				t.Errorf("ValidateToken() error = %v", err)
+				return
+			}
+			results <- claims
+		}()
+	}
+
+	// Wait until every caller but the leader is waiting on the in-flight request
+	deadline := time.Now().Add(5 * time.Second)
+	for validator.CoalescedCalls() < concurrency-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("CoalescedCalls() = %d, want %d", validator.CoalescedCalls(), concurrency-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("introspection calls = %d, want 1", got)
+	}
+
+	// Every caller gets its own copy of the claims
+	seen := make(map[*AuthClaims]bool)
+	for claims := range results {
+		if claims.Subject != "user-123" {
+			t.Errorf("Subject = %v, want user-123", claims.Subject)
+		}
+		if seen[claims] {
+			t.Error("callers must not share the same AuthClaims pointer")
+		}
+		seen[claims] = true
+	}
+}
+
+func TestIntrospectionValidator_DistinctTokensAreNotCoalesced(t *testing.T) {
+	var calls atomic.Int32
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			responseBody, _ := json.Marshal(TokenIntrospectionResponse{Active: true, Sub: "user-123"})
+			return createMockResponse(http.StatusOK, string(responseBody)), nil
+		},
+	}
+
+	validator := mustIntrospectionValidator(t, config.AuthConfig{}, mockClient)
+	validator.ValidateToken(context.Background(), "token-a")
+	validator.ValidateToken(context.Background(), "token-b")
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("introspection calls = %d, want 2", got)
+	}
+	if got := validator.CoalescedCalls(); got != 0 {
+		t.Errorf("CoalescedCalls() = %d, want 0", got)
+	}
+}
@@ -2,15 +2,46 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"slices"
 
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
 )
 
+// ErrTenantViolation is returned when an event is written to an organization outside the tenant
+var ErrTenantViolation = errors.New("event organization is outside the tenant")
+
+// Tenant scopes event operations to the events of the organizations the caller may access
+// Events of other organizations are treated as if they did not exist
+type Tenant struct {
+	Organizations []string // Keycloak organization aliases; none matches no events
+	Subject       string   // the caller's sub claim, for auditing
+	Admin         bool     // the caller is a system administrator; the database bypasses row-level security
+}
+
+// Includes reports whether the tenant may access events of the organization
+func (t Tenant) Includes(organization string) bool {
+	return slices.Contains(t.Organizations, organization)
+}
+
 // EventsRepository defines the interface for event data operations
 type EventsRepository interface {
-	// GetEvents retrieves all events
-	GetEvents(ctx context.Context) (models.Events, error)
+	// GetEvents retrieves all events of the tenant's organizations
+	GetEvents(ctx context.Context, tenant Tenant) (models.Events, error)
 
 	// GetEventByID retrieves a specific event by its ID
-	GetEventByID(ctx context.Context, id string) (*models.Event, error)
+	// Returns nil, nil when no event with that ID exists in the tenant's organizations
+	GetEventByID(ctx context.Context, tenant Tenant, id string) (*models.Event, error)
+
+	// CreateEvent stores a new event; the event ID and organization must already be set
+	// Returns ErrTenantViolation when the organization is not one of the tenant's
+	CreateEvent(ctx context.Context, tenant Tenant, event *models.Event) error
+
+	// UpdateEvent replaces an existing event identified by event.ID; its organization is kept
+	// Returns nil, nil when no event with that ID exists in the tenant's organizations
+	UpdateEvent(ctx context.Context, tenant Tenant, event *models.Event) (*models.Event, error)
+
+	// DeleteEvent removes the event with the given ID
+	// Returns false when no event with that ID exists in the tenant's organizations
+	DeleteEvent(ctx context.Context, tenant Tenant, id string) (bool, error)
 }
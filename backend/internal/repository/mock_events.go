@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,19 +14,30 @@ import (
 type MockEventsRepository struct {
 	// Store a fixed event ID for testing GetEventByID
 	FixedEventID string
+
+	// Organization of the seeded events, including FixedEventID
+	FixedOrganization string
+
+	// Event of another organization, for testing tenant isolation
+	OtherOrganization string
+	OtherEventID      string
+
+	mu     sync.RWMutex
+	events map[string]models.Event
 }
 
 // NewMockEventsRepository creates a new MockEventsRepository
 func NewMockEventsRepository() *MockEventsRepository {
-	return &MockEventsRepository{
-		FixedEventID: "event-123", // Fixed ID for testing
+	r := &MockEventsRepository{
+		FixedEventID:      "event-123", // Fixed ID for testing
+		FixedOrganization: "org-123",
+		OtherOrganization: "org-456",
+		OtherEventID:      "event-456",
+		events:            make(map[string]models.Event),
 	}
-}
 
-// GetEvents returns mock events for testing
-func (r *MockEventsRepository) GetEvents(ctx context.Context) (models.Events, error) {
-	// Create some mock events
-	events := models.Events{
+	// Seed some mock events
+	seed := models.Events{
 		{
 			ID:          r.FixedEventID, // Use the fixed ID for the first event
 			Date:        time.Now().AddDate(0, 0, 7),
@@ -47,23 +60,90 @@ func (r *MockEventsRepository) GetEvents(ctx context.Context) (models.Events, er
 			Location:    "Community Pool",
 		},
 	}
+	for _, event := range seed {
+		event.Organization = r.FixedOrganization
+		r.events[event.ID] = event
+	}
+	r.events[r.OtherEventID] = models.Event{
+		ID:           r.OtherEventID,
+		Date:         time.Now().AddDate(0, 0, 10),
+		Title:        "Neighbouring Club Training",
+		Description:  "Open training session of the neighbouring club",
+		Location:     "Riverside Field",
+		Organization: r.OtherOrganization,
+	}
+
+	return r
+}
+
+// GetEvents returns the mock events of the tenant's organizations, ordered by date
+func (r *MockEventsRepository) GetEvents(ctx context.Context, tenant Tenant) (models.Events, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make(models.Events, 0, len(r.events))
+	for _, event := range r.events {
+		if tenant.Includes(event.Organization) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
 
 	return events, nil
 }
 
-// GetEventByID returns a mock event for testing
-func (r *MockEventsRepository) GetEventByID(ctx context.Context, id string) (*models.Event, error) {
-	// If the ID matches our fixed ID, return a mock event
-	if id == r.FixedEventID {
-		return &models.Event{
-			ID:          r.FixedEventID,
-			Date:        time.Now().AddDate(0, 0, 7),
-			Title:       "Community Soccer Match",
-			Description: "Weekly soccer match for all community members",
-			Location:    "Community Field",
-		}, nil
+// GetEventByID returns a mock event of the tenant's organizations
+func (r *MockEventsRepository) GetEventByID(ctx context.Context, tenant Tenant, id string) (*models.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, ok := r.events[id]
+	if !ok || !tenant.Includes(event.Organization) {
+		// If the ID doesn't exist, return nil to simulate not found
+		return nil, nil
+	}
+	return &event, nil
+}
+
+// CreateEvent stores a new mock event in one of the tenant's organizations
+func (r *MockEventsRepository) CreateEvent(ctx context.Context, tenant Tenant, event *models.Event) error {
+	if !tenant.Includes(event.Organization) {
+		return ErrTenantViolation
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[event.ID] = *event
+	return nil
+}
+
+// UpdateEvent replaces an existing mock event of the tenant's organizations
+func (r *MockEventsRepository) UpdateEvent(ctx context.Context, tenant Tenant, event *models.Event) (*models.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.events[event.ID]
+	if !ok || !tenant.Includes(existing.Organization) {
+		return nil, nil
 	}
+	updated := *event
+	updated.Organization = existing.Organization
+	r.events[event.ID] = updated
 
-	// If the ID doesn't match, return nil to simulate not found
-	return nil, nil
+	return &updated, nil
+}
+
+// DeleteEvent removes a mock event of the tenant's organizations
+func (r *MockEventsRepository) DeleteEvent(ctx context.Context, tenant Tenant, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event, ok := r.events[id]; !ok || !tenant.Includes(event.Organization) {
+		return false, nil
+	}
+	delete(r.events, id)
+	return true, nil
 }
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+)
+
+// MockTokenRevocationRepository implements TokenRevocationRepository in memory for testing
+type MockTokenRevocationRepository struct {
+	mu      sync.RWMutex
+	revoked map[string]models.RevokedToken
+	now     func() time.Time
+}
+
+// NewMockTokenRevocationRepository creates a new, empty MockTokenRevocationRepository
+func NewMockTokenRevocationRepository() *MockTokenRevocationRepository {
+	return &MockTokenRevocationRepository{
+		revoked: make(map[string]models.RevokedToken),
+		now:     time.Now,
+	}
+}
+
+// RevokeToken adds a denylist entry, keeping the later expiry for an existing jti
+func (r *MockTokenRevocationRepository) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := *token
+	if existing, ok := r.revoked[token.JTI]; ok && existing.ExpiresAt.After(entry.ExpiresAt) {
+		entry.ExpiresAt = existing.ExpiresAt
+	}
+	r.revoked[token.JTI] = entry
+	return nil
+}
+
+// IsTokenRevoked reports whether an unexpired denylist entry exists for the jti
+func (r *MockTokenRevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.revoked[jti]
+	return ok && entry.ExpiresAt.After(r.now()), nil
+}
+
+// PurgeExpiredRevocations removes entries that expired before the given time
+func (r *MockTokenRevocationRepository) PurgeExpiredRevocations(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for jti, entry := range r.revoked {
+		if entry.ExpiresAt.Before(before) {
+			delete(r.revoked, jti)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -6,10 +6,21 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
 )
 
+// rlsBypassRole is the database role assumed for system administrators, which
+// bypasses the row-level security policy on events.events
+const rlsBypassRole = "events_admin"
+
+// insufficientPrivilege is the SQLSTATE of a row-level security policy violation
+const insufficientPrivilege = "42501"
+
 // PostgresEventsRepository implements EventsRepository using PostgreSQL
+// Every operation runs in a transaction scoped to the tenant, so the row-level
+// security policy on events.events enforces tenant isolation in addition to the
+// organization filter of each query
 type PostgresEventsRepository struct {
 	db *sql.DB
 }
@@ -21,54 +32,91 @@ func NewPostgresEventsRepository(db *sql.DB) *PostgresEventsRepository {
 	}
 }
 
-// GetEvents retrieves all events from the database
-func (r *PostgresEventsRepository) GetEvents(ctx context.Context) (models.Events, error) {
+// inTenant runs fn in a transaction with the tenant's app.current_org and app.current_sub
+// settings, assuming rlsBypassRole for administrators
+// The transaction is committed if fn succeeds and rolled back otherwise
+func (r *PostgresEventsRepository) inTenant(ctx context.Context, tenant Tenant, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Transaction-local settings, so they never leak to other requests via the pool
+	// The organizations are stored as an array literal, so aliases may contain any character
+	_, err = tx.ExecContext(ctx,
+		`SELECT set_config('app.current_org', $1::text[]::text, true), set_config('app.current_sub', $2, true)`,
+		pq.Array(tenant.Organizations), tenant.Subject,
+	)
+	if err != nil {
+		return err
+	}
+	if tenant.Admin {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL ROLE "+pq.QuoteIdentifier(rlsBypassRole)); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetEvents retrieves all events of the tenant's organizations from the database
+func (r *PostgresEventsRepository) GetEvents(ctx context.Context, tenant Tenant) (models.Events, error) {
 	query := `
-		SELECT id, date, title, description, location
+		SELECT id, date, title, description, location, organization
 		FROM events.events
+		WHERE organization = ANY($1)
 		ORDER BY date ASC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	var events models.Events
-	for rows.Next() {
-		var event models.Event
-		var date time.Time
-
-		if err := rows.Scan(&event.ID, &date, &event.Title, &event.Description, &event.Location); err != nil {
-			return nil, err
+	err := r.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, pq.Array(tenant.Organizations))
+		if err != nil {
+			return err
 		}
+		defer rows.Close()
 
-		event.Date = date
-		events = append(events, event)
-	}
+		for rows.Next() {
+			var event models.Event
+			var date time.Time
+
+			if err := rows.Scan(&event.ID, &date, &event.Title, &event.Description, &event.Location, &event.Organization); err != nil {
+				return err
+			}
 
-	if err := rows.Err(); err != nil {
+			event.Date = date
+			events = append(events, event)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return events, nil
 }
 
-// GetEventByID retrieves a specific event by its ID from the database
-func (r *PostgresEventsRepository) GetEventByID(ctx context.Context, id string) (*models.Event, error) {
+// GetEventByID retrieves a specific event of the tenant's organizations by its ID from the database
+func (r *PostgresEventsRepository) GetEventByID(ctx context.Context, tenant Tenant, id string) (*models.Event, error) {
 	query := `
-		SELECT id, date, title, description, location
+		SELECT id, date, title, description, location, organization
 		FROM events.events
-		WHERE id = $1
+		WHERE id = $1 AND organization = ANY($2)
 	`
 
 	var event models.Event
 	var date time.Time
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&event.ID, &date, &event.Title, &event.Description, &event.Location,
-	)
+	err := r.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, id, pq.Array(tenant.Organizations)).Scan(
+			&event.ID, &date, &event.Title, &event.Description, &event.Location, &event.Organization,
+		)
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -80,3 +128,76 @@ func (r *PostgresEventsRepository) GetEventByID(ctx context.Context, id string)
 	event.Date = date
 	return &event, nil
 }
+
+// CreateEvent inserts a new event into the database
+func (r *PostgresEventsRepository) CreateEvent(ctx context.Context, tenant Tenant, event *models.Event) error {
+	query := `
+		INSERT INTO events.events (id, date, title, description, location, organization)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	err := r.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query,
+			event.ID, event.Date, event.Title, event.Description, event.Location, event.Organization,
+		)
+		return err
+	})
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == insufficientPrivilege {
+		return ErrTenantViolation
+	}
+	return err
+}
+
+// UpdateEvent updates an existing event of the tenant's organizations in the database
+func (r *PostgresEventsRepository) UpdateEvent(ctx context.Context, tenant Tenant, event *models.Event) (*models.Event, error) {
+	query := `
+		UPDATE events.events
+		SET date = $2, title = $3, description = $4, location = $5
+		WHERE id = $1 AND organization = ANY($6)
+		RETURNING id, date, title, description, location, organization
+	`
+
+	var updated models.Event
+	var date time.Time
+
+	err := r.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query,
+			event.ID, event.Date, event.Title, event.Description, event.Location, pq.Array(tenant.Organizations),
+		).Scan(&updated.ID, &date, &updated.Title, &updated.Description, &updated.Location, &updated.Organization)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Return nil, nil when no event is found
+		}
+		return nil, err
+	}
+
+	updated.Date = date
+	return &updated, nil
+}
+
+// DeleteEvent deletes an event of the tenant's organizations by its ID from the database
+func (r *PostgresEventsRepository) DeleteEvent(ctx context.Context, tenant Tenant, id string) (bool, error) {
+	query := `
+		DELETE FROM events.events
+		WHERE id = $1 AND organization = ANY($2)
+	`
+
+	var affected int64
+	err := r.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, id, pq.Array(tenant.Organizations))
+		if err != nil {
+			return err
+		}
+		affected, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
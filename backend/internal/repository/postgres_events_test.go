@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+)
+
+// openTestDatabase connects to TEST_DATABASE_URL, a database initialized with the
+// scripts in data/db, as a role subject to row-level security (e.g. events_user)
+// The test is skipped if TEST_DATABASE_URL is not set
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// A single connection shows that transaction settings do not outlive the transaction
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func TestPostgresEventsRepository_RowLevelSecurity(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := NewPostgresEventsRepository(db)
+	ctx := context.Background()
+
+	org, other := "rls-test-"+uuid.NewString(), "rls-other-"+uuid.NewString()
+	tenant := Tenant{Organizations: []string{org}, Subject: "user-123"}
+	otherTenant := Tenant{Organizations: []string{other}, Subject: "user-456"}
+
+	event := &models.Event{ID: uuid.NewString(), Date: time.Now(), Title: "RLS Test", Location: "Test Field", Organization: org}
+	otherEvent := &models.Event{ID: uuid.NewString(), Date: time.Now(), Title: "RLS Test", Location: "Test Field", Organization: other}
+	if err := repo.CreateEvent(ctx, tenant, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+	if err := repo.CreateEvent(ctx, otherTenant, otherEvent); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+	t.Cleanup(func() {
+		repo.DeleteEvent(ctx, tenant, event.ID)
+		repo.DeleteEvent(ctx, otherTenant, otherEvent.ID)
+	})
+
+	// countEvents counts the test events visible in a transaction of the tenant,
+	// without the organization filter of the repository queries
+	countEvents := func(t *testing.T, tenant Tenant) int {
+		t.Helper()
+		var count int
+		err := repo.inTenant(ctx, tenant, func(tx *sql.Tx) error {
+			return tx.QueryRowContext(ctx,
+				`SELECT count(*) FROM events.events WHERE organization IN ($1, $2)`, org, other,
+			).Scan(&count)
+		})
+		if err != nil {
+			t.Fatalf("failed to count events: %v", err)
+		}
+		return count
+	}
+
+	t.Run("Query without setting returns nothing", func(t *testing.T) {
+		var count int
+		if err := db.QueryRowContext(ctx, `SELECT count(*) FROM events.events`).Scan(&count); err != nil {
+			t.Fatalf("failed to count events: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("count = %d, want 0", count)
+		}
+	})
+
+	t.Run("Setting does not outlive the transaction", func(t *testing.T) {
+		var setting sql.NullString
+		if err := db.QueryRowContext(ctx, `SELECT current_setting('app.current_org', true)`).Scan(&setting); err != nil {
+			t.Fatalf("failed to read setting: %v", err)
+		}
+		if setting.String != "" {
+			t.Errorf("app.current_org = %q, want empty", setting.String)
+		}
+	})
+
+	t.Run("Policy hides other organizations", func(t *testing.T) {
+		if got := countEvents(t, tenant); got != 1 {
+			t.Errorf("count = %d, want 1", got)
+		}
+		if got := countEvents(t, Tenant{Organizations: []string{org, other}}); got != 2 {
+			t.Errorf("count for both organizations = %d, want 2", got)
+		}
+		if got := countEvents(t, Tenant{}); got != 0 {
+			t.Errorf("count without organizations = %d, want 0", got)
+		}
+	})
+
+	t.Run("Alias with a comma does not widen the policy", func(t *testing.T) {
+		if got := countEvents(t, Tenant{Organizations: []string{org + "," + other}}); got != 0 {
+			t.Errorf("count = %d, want 0", got)
+		}
+	})
+
+	t.Run("Admin bypasses policy", func(t *testing.T) {
+		if got := countEvents(t, Tenant{Admin: true}); got != 2 {
+			t.Errorf("count = %d, want 2", got)
+		}
+	})
+
+	t.Run("Repository is scoped to tenant", func(t *testing.T) {
+		events, err := repo.GetEvents(ctx, tenant)
+		if err != nil {
+			t.Fatalf("GetEvents() error = %v", err)
+		}
+		if len(events) != 1 || events[0].ID != event.ID {
+			t.Errorf("GetEvents() = %v, want only %s", events, event.ID)
+		}
+		if got, err := repo.GetEventByID(ctx, tenant, otherEvent.ID); err != nil || got != nil {
+			t.Errorf("GetEventByID() of other organization = %v, %v, want nil, nil", got, err)
+		}
+	})
+
+	t.Run("Insert into other organization is rejected", func(t *testing.T) {
+		foreign := &models.Event{ID: uuid.NewString(), Date: time.Now(), Title: "RLS Test", Location: "Test Field", Organization: other}
+		if err := repo.CreateEvent(ctx, tenant, foreign); !errors.Is(err, ErrTenantViolation) {
+			t.Errorf("CreateEvent() error = %v, want ErrTenantViolation", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+)
+
+// PostgresTokenRevocationRepository implements TokenRevocationRepository using PostgreSQL
+type PostgresTokenRevocationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTokenRevocationRepository creates a new PostgresTokenRevocationRepository
+func NewPostgresTokenRevocationRepository(db *sql.DB) *PostgresTokenRevocationRepository {
+	return &PostgresTokenRevocationRepository{
+		db: db,
+	}
+}
+
+// RevokeToken inserts a denylist entry into the database
+func (r *PostgresTokenRevocationRepository) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	query := `
+		INSERT INTO events.revoked_tokens (jti, expires_at, reason, revoked_by, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (jti) DO UPDATE
+		SET expires_at = GREATEST(events.revoked_tokens.expires_at, EXCLUDED.expires_at),
+		    reason = EXCLUDED.reason,
+		    revoked_by = EXCLUDED.revoked_by,
+		    revoked_at = EXCLUDED.revoked_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		token.JTI, token.ExpiresAt, token.Reason, token.RevokedBy, token.RevokedAt,
+	)
+	return err
+}
+
+// IsTokenRevoked checks the database for an unexpired denylist entry
+func (r *PostgresTokenRevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM events.revoked_tokens
+			WHERE jti = $1 AND expires_at > NOW()
+		)
+	`
+
+	var revoked bool
+	if err := r.db.QueryRowContext(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// PurgeExpiredRevocations deletes expired denylist entries from the database
+func (r *PostgresTokenRevocationRepository) PurgeExpiredRevocations(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM events.revoked_tokens
+		WHERE expires_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
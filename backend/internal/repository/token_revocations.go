@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/schneefisch/oauth_keycloak_demo/backend/internal/models"
+)
+
+// TokenRevocationRepository defines the interface for the access token denylist
+type TokenRevocationRepository interface {
+	// RevokeToken adds a token to the denylist
+	// Revoking an already revoked jti keeps the later expiry and replaces the reason
+	RevokeToken(ctx context.Context, token *models.RevokedToken) error
+
+	// IsTokenRevoked reports whether an unexpired denylist entry exists for the jti
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	// PurgeExpiredRevocations removes entries that expired before the given time
+	// Returns the number of removed entries
+	PurgeExpiredRevocations(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PurgeExpiredRevocationsPeriodically removes expired denylist entries every interval
+// until the context is canceled
+func PurgeExpiredRevocationsPeriodically(ctx context.Context, repo TokenRevocationRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := repo.PurgeExpiredRevocations(ctx, time.Now())
+			if err != nil {
+				log.Printf("Error purging expired token revocations: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d expired token revocations", purged)
+			}
+		}
+	}
+}
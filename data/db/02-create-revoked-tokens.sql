@@ -0,0 +1,14 @@
+-- Denylist of individually revoked access tokens, identified by their jti claim
+-- Entries are purged by the backend once the token has expired
+CREATE TABLE IF NOT EXISTS events.revoked_tokens (
+    jti VARCHAR(255) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL,
+    revoked_by VARCHAR(255),
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON events.revoked_tokens (expires_at);
+
+-- Grant privileges to the events user
+GRANT ALL PRIVILEGES ON events.revoked_tokens TO events_user;
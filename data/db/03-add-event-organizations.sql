@@ -0,0 +1,10 @@
+-- Events belong to a Keycloak organization, identified by its alias
+ALTER TABLE events.events ADD COLUMN IF NOT EXISTS organization VARCHAR(255);
+
+-- Assign existing events to the demo association
+UPDATE events.events SET organization = 'demo-association' WHERE organization IS NULL;
+
+ALTER TABLE events.events ALTER COLUMN organization SET NOT NULL;
+
+-- Every query filters on the organization
+CREATE INDEX IF NOT EXISTS events_organization_date_idx ON events.events (organization, date);
@@ -0,0 +1,24 @@
+-- Row-level security: the backend runs every events query in a transaction that sets
+--   app.current_org - the aliases of the caller's organizations as a text[] literal, e.g. {acme,"a,b"}
+--   app.current_sub - the caller's subject, for auditing
+-- Without app.current_org, no events are visible and none can be written
+ALTER TABLE events.events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE events.events FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS events_tenant_isolation ON events.events;
+CREATE POLICY events_tenant_isolation ON events.events
+    USING (organization = ANY (NULLIF(current_setting('app.current_org', true), '')::text[]))
+    WITH CHECK (organization = ANY (NULLIF(current_setting('app.current_org', true), '')::text[]));
+
+-- Role for system administrators, assumed with SET LOCAL ROLE, that bypasses the policy
+DO $$
+BEGIN
+    CREATE ROLE events_admin NOLOGIN BYPASSRLS;
+EXCEPTION WHEN duplicate_object THEN
+    NULL;
+END
+$$;
+
+GRANT USAGE ON SCHEMA events TO events_admin;
+GRANT ALL PRIVILEGES ON events.events TO events_admin;
+GRANT events_admin TO events_user;